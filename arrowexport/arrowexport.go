@@ -0,0 +1,199 @@
+// Package arrowexport reads dingo rows as arrow record batches, it needs go1.25 required by arrow-go v18
+package arrowexport
+
+import (
+	"fmt"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	dingo "github.com/modern-go/sqlbind"
+	"strconv"
+	"time"
+)
+
+/*
+record batch is copied from dingo.Batch column by column, the schema is fixed for every batch of the reader.
+if the schema is not given, it follows the column type of the first batch:
+bool => Boolean, int64 => Int64, float64 => Float64, time => Timestamp(us, UTC), string and only NULL => String.
+later batch column is widened to the schema type: bool => Int64 => Float64, any type => String,
+column with only NULL fits any type, other column type is error.
+NULL is marked invalid in the validity bitmap
+*/
+
+type Reader struct {
+	rows    *dingo.Rows
+	schema  *arrow.Schema
+	maxRows int
+	batch   *dingo.Batch
+}
+
+// NewReader reads at most maxRows rows per record batch, nil schema means following the first batch
+func NewReader(rows *dingo.Rows, schema *arrow.Schema, maxRows int) *Reader {
+	return &Reader{rows, schema, maxRows, dingo.NewBatch()}
+}
+
+// Schema is nil before the first Next if the schema is not given
+func (reader *Reader) Schema() *arrow.Schema {
+	return reader.schema
+}
+
+// Next reads the next record batch, io.EOF if there is no more row, the caller should Release the record batch
+func (reader *Reader) Next() (arrow.RecordBatch, error) {
+	batch := reader.batch
+	if err := reader.rows.NextBatch(batch, reader.maxRows); err != nil {
+		return nil, err
+	}
+	if reader.schema == nil {
+		fields := make([]arrow.Field, len(batch.Columns()))
+		for i, column := range batch.Columns() {
+			fields[i] = arrow.Field{Name: column, Type: arrowTypeOf(batch.Column(column)), Nullable: true}
+		}
+		reader.schema = arrow.NewSchema(fields, nil)
+	}
+	builder := array.NewRecordBuilder(memory.DefaultAllocator, reader.schema)
+	defer builder.Release()
+	for i := 0; i < reader.schema.NumFields(); i++ {
+		field := reader.schema.Field(i)
+		colData := batch.Column(field.Name)
+		if colData == nil {
+			return nil, fmt.Errorf("column %v of schema not found in rows", field.Name)
+		}
+		if err := appendColumn(builder.Field(i), field, colData, batch.NullColumn(field.Name)); err != nil {
+			return nil, err
+		}
+	}
+	return builder.NewRecordBatch(), nil
+}
+
+func arrowTypeOf(colData interface{}) arrow.DataType {
+	switch colData.(type) {
+	case []bool:
+		return arrow.FixedWidthTypes.Boolean
+	case []int64:
+		return arrow.PrimitiveTypes.Int64
+	case []float64:
+		return arrow.PrimitiveTypes.Float64
+	case []time.Time:
+		return &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+	}
+	return arrow.BinaryTypes.String
+}
+
+// appendColumn widens the column data to the builder type of the schema field
+func appendColumn(builder array.Builder, field arrow.Field, colData interface{}, nulls []bool) error {
+	valid := make([]bool, len(nulls))
+	allNull := true
+	for j, isNull := range nulls {
+		valid[j] = !isNull
+		allNull = allNull && isNull
+	}
+	if allNull {
+		for range nulls {
+			builder.AppendNull()
+		}
+		return nil
+	}
+	switch typedBuilder := builder.(type) {
+	case *array.BooleanBuilder:
+		if bools, ok := colData.([]bool); ok {
+			typedBuilder.AppendValues(bools, valid)
+			return nil
+		}
+	case *array.Int64Builder:
+		switch typedData := colData.(type) {
+		case []int64:
+			typedBuilder.AppendValues(typedData, valid)
+			return nil
+		case []bool:
+			typedBuilder.AppendValues(boolsToInt64s(typedData), valid)
+			return nil
+		}
+	case *array.Float64Builder:
+		switch typedData := colData.(type) {
+		case []float64:
+			typedBuilder.AppendValues(typedData, valid)
+			return nil
+		case []int64:
+			float64s := make([]float64, len(typedData))
+			for j, val := range typedData {
+				float64s[j] = float64(val)
+			}
+			typedBuilder.AppendValues(float64s, valid)
+			return nil
+		case []bool:
+			float64s := make([]float64, len(typedData))
+			for j, val := range boolsToInt64s(typedData) {
+				float64s[j] = float64(val)
+			}
+			typedBuilder.AppendValues(float64s, valid)
+			return nil
+		}
+	case *array.TimestampBuilder:
+		if times, ok := colData.([]time.Time); ok {
+			unit := arrow.Microsecond
+			if timestampType, isTimestamp := field.Type.(*arrow.TimestampType); isTimestamp {
+				unit = timestampType.Unit
+			}
+			timestamps := make([]arrow.Timestamp, len(times))
+			for j, val := range times {
+				if !valid[j] {
+					continue
+				}
+				timestamp, err := arrow.TimestampFromTime(val, unit)
+				if err != nil {
+					return fmt.Errorf("column %v: %w", field.Name, err)
+				}
+				timestamps[j] = timestamp
+			}
+			typedBuilder.AppendValues(timestamps, valid)
+			return nil
+		}
+	case *array.StringBuilder:
+		typedBuilder.AppendValues(toStrings(colData), valid)
+		return nil
+	}
+	return fmt.Errorf("column %v of %T does not fit %v of schema", field.Name, colData, field.Type.Name())
+}
+
+func boolsToInt64s(bools []bool) []int64 {
+	int64s := make([]int64, len(bools))
+	for j, val := range bools {
+		if val {
+			int64s[j] = 1
+		}
+	}
+	return int64s
+}
+
+// toStrings formats like dingo.Batch promoting column to string
+func toStrings(colData interface{}) []string {
+	switch typedData := colData.(type) {
+	case []string:
+		return typedData
+	case []bool:
+		strs := make([]string, len(typedData))
+		for j, val := range typedData {
+			strs[j] = strconv.FormatBool(val)
+		}
+		return strs
+	case []int64:
+		strs := make([]string, len(typedData))
+		for j, val := range typedData {
+			strs[j] = strconv.FormatInt(val, 10)
+		}
+		return strs
+	case []float64:
+		strs := make([]string, len(typedData))
+		for j, val := range typedData {
+			strs[j] = fmt.Sprintf("%v", val)
+		}
+		return strs
+	case []time.Time:
+		strs := make([]string, len(typedData))
+		for j, val := range typedData {
+			strs[j] = val.Format("2006-01-02 15:04:05")
+		}
+		return strs
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package arrowexport
+
+import (
+	"database/sql/driver"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	dingo "github.com/modern-go/sqlbind"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func Test_next_record_batch(t *testing.T) {
+	should := require.New(t)
+	now := time.Date(2018, 1, 2, 3, 4, 5, 6000, time.UTC)
+	rows := queryFakeRows([]string{"entity_id", "version", "balance", "updated_at", "enabled"},
+		[]driver.Value{[]byte("account1"), int64(1), float64(1.5), now, true},
+		[]driver.Value{nil, nil, nil, nil, nil},
+		[]driver.Value{[]byte("account3"), int64(3), float64(3), now, false})
+	defer rows.Close()
+	reader := NewReader(rows, nil, 2)
+	should.Nil(reader.Schema())
+	record, err := reader.Next()
+	should.Nil(err)
+	defer record.Release()
+	should.Equal(int64(2), record.NumRows())
+	should.Equal("entity_id", record.Schema().Field(0).Name)
+	should.Equal(arrow.PrimitiveTypes.Int64, record.Schema().Field(1).Type)
+	should.Equal("account1", record.Column(0).(*array.String).Value(0))
+	should.True(record.Column(0).IsNull(1))
+	should.Equal(int64(1), record.Column(1).(*array.Int64).Value(0))
+	should.True(record.Column(1).IsNull(1))
+	should.Equal(1.5, record.Column(2).(*array.Float64).Value(0))
+	should.Equal(arrow.Timestamp(now.UnixMicro()), record.Column(3).(*array.Timestamp).Value(0))
+	should.True(record.Column(4).(*array.Boolean).Value(0))
+	should.Equal(1, record.Column(4).NullN())
+	record, err = reader.Next()
+	should.Nil(err)
+	should.Equal(int64(1), record.NumRows())
+	should.Equal(int64(3), record.Column(1).(*array.Int64).Value(0))
+	record.Release()
+	_, err = reader.Next()
+	should.Equal(io.EOF, err)
+}
+
+func Test_schema_fixed_by_first_batch(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"entity_id", "version", "balance"},
+		[]driver.Value{[]byte("account1"), nil, int64(1)},
+		[]driver.Value{[]byte("account2"), int64(2), true},
+		[]driver.Value{[]byte("account3"), int64(3), float64(2.5)})
+	defer rows.Close()
+	reader := NewReader(rows, nil, 1)
+	record, err := reader.Next()
+	should.Nil(err)
+	schema := reader.Schema()
+	should.Equal(arrow.BinaryTypes.String, schema.Field(1).Type)
+	should.Equal(arrow.PrimitiveTypes.Int64, schema.Field(2).Type)
+	should.True(record.Column(1).IsNull(0))
+	record.Release()
+	record, err = reader.Next()
+	should.Nil(err)
+	should.Equal(schema, record.Schema())
+	should.Equal("2", record.Column(1).(*array.String).Value(0))
+	should.Equal(int64(1), record.Column(2).(*array.Int64).Value(0))
+	record.Release()
+	_, err = reader.Next()
+	should.NotNil(err)
+}
+
+func Test_explicit_schema(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"entity_id", "version", "balance"},
+		[]driver.Value{[]byte("account1"), nil, int64(1)},
+		[]driver.Value{[]byte("account2"), int64(2), true})
+	defer rows.Close()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "balance", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "version", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)
+	reader := NewReader(rows, schema, 1)
+	record, err := reader.Next()
+	should.Nil(err)
+	should.Equal(schema, record.Schema())
+	should.Equal(1.0, record.Column(0).(*array.Float64).Value(0))
+	should.True(record.Column(1).IsNull(0))
+	record.Release()
+	record, err = reader.Next()
+	should.Nil(err)
+	should.Equal(1.0, record.Column(0).(*array.Float64).Value(0))
+	should.Equal(int64(2), record.Column(1).(*array.Int64).Value(0))
+	record.Release()
+	rows = queryFakeRows([]string{"entity_id"}, []driver.Value{[]byte("account1")})
+	defer rows.Close()
+	_, err = NewReader(rows, schema, 1).Next()
+	should.NotNil(err)
+}
+
+func queryFakeRows(columns []string, data ...[]driver.Value) *dingo.Rows {
+	conn, err := dingo.Open(&fakeDriver{&fakeRows{columns, data}}, "fake")
+	if err != nil {
+		panic(err)
+	}
+	rows, err := conn.TranslateStatement("SELECT * FROM account").Query()
+	if err != nil {
+		panic(err)
+	}
+	return rows.(*dingo.Rows)
+}
+
+type fakeDriver struct {
+	rows *fakeRows
+}
+
+func (drv *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	return &fakeConn{drv.rows}, nil
+}
+
+type fakeConn struct {
+	rows *fakeRows
+}
+
+func (conn *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn}, nil
+}
+
+func (conn *fakeConn) Close() error {
+	return nil
+}
+
+func (conn *fakeConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+type fakeStmt struct {
+	conn *fakeConn
+}
+
+func (stmt *fakeStmt) Close() error {
+	return nil
+}
+
+func (stmt *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (stmt *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), nil
+}
+
+func (stmt *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return stmt.conn.rows, nil
+}
+
+type fakeRows struct {
+	columns []string
+	data    [][]driver.Value
+}
+
+func (rows *fakeRows) Columns() []string {
+	return rows.columns
+}
+
+func (rows *fakeRows) Close() error {
+	return nil
+}
+
+func (rows *fakeRows) Next(dest []driver.Value) error {
+	if len(rows.data) == 0 {
+		return io.EOF
+	}
+	copy(dest, rows.data[0])
+	rows.data = rows.data[1:]
+	return nil
+}
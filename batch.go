@@ -0,0 +1,351 @@
+package dingo
+
+import (
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"time"
+)
+
+/*
+batch reads rows column by column, the column type is decided by the values read:
+bool, int64, float64, time.Time, and string for []byte, string and any other type.
+column with only NULL is read as string.
+if a later row does not fit the column type, the rows already read are converted:
+bool => int64 => float64 => string, time and any other type => string
+*/
+
+type Batch struct {
+	len     int
+	data    map[string]interface{}
+	columns []string
+	// column => null mask, true if the row is NULL
+	nulls map[string][]bool
+}
+
+func NewBatch() *Batch {
+	return &Batch{0, map[string]interface{}{}, nil, map[string][]bool{}}
+}
+
+func (batch *Batch) Len() int {
+	return batch.len
+}
+
+func (batch *Batch) Columns() []string {
+	return batch.columns
+}
+
+// IsNull tells the column of the row is NULL, the value in typed column is zero value.
+// false if the column is not in the batch
+func (batch *Batch) IsNull(row int, column string) bool {
+	nulls, found := batch.nulls[column]
+	if !found || row >= batch.len {
+		return false
+	}
+	return nulls[row]
+}
+
+// Column is the typed column data, []bool, []int64, []float64, []time.Time or []string,
+// nil if the column is not in the batch
+func (batch *Batch) Column(column string) interface{} {
+	switch colData := batch.data[column].(type) {
+	case []bool:
+		return colData[:batch.len]
+	case []int64:
+		return colData[:batch.len]
+	case []float64:
+		return colData[:batch.len]
+	case []time.Time:
+		return colData[:batch.len]
+	case []string:
+		return colData[:batch.len]
+	}
+	return nil
+}
+
+// NullColumn is the null mask of the column, true if the row is NULL.
+// nil if the column is not in the batch
+func (batch *Batch) NullColumn(column string) []bool {
+	nulls, found := batch.nulls[column]
+	if !found {
+		return nil
+	}
+	return nulls[:batch.len]
+}
+
+func (batch *Batch) GetStringColumn(column string) []string {
+	colData := batch.data[column].([]string)
+	return colData[:batch.len]
+}
+
+func (batch *Batch) GetString(row int, column string) string {
+	return batch.GetStringColumn(column)[row]
+}
+
+func (batch *Batch) GetInt64Column(column string) []int64 {
+	colData := batch.data[column].([]int64)
+	return colData[:batch.len]
+}
+
+func (batch *Batch) GetInt(row int, column string) int {
+	return int(batch.GetInt64Column(column)[row])
+}
+
+func (batch *Batch) GetFloat64Column(column string) []float64 {
+	colData := batch.data[column].([]float64)
+	return colData[:batch.len]
+}
+
+func (batch *Batch) GetFloat64(row int, column string) float64 {
+	return batch.GetFloat64Column(column)[row]
+}
+
+func (batch *Batch) GetTimeColumn(column string) []time.Time {
+	colData := batch.data[column].([]time.Time)
+	return colData[:batch.len]
+}
+
+func (batch *Batch) GetTime(row int, column string) time.Time {
+	return batch.GetTimeColumn(column)[row]
+}
+
+func (batch *Batch) GetBoolColumn(column string) []bool {
+	colData := batch.data[column].([]bool)
+	return colData[:batch.len]
+}
+
+func (batch *Batch) GetBool(row int, column string) bool {
+	return batch.GetBoolColumn(column)[row]
+}
+
+// NextBatch reads at most maxToRead rows into batch, io.EOF if there is no more row
+func (rows *Rows) NextBatch(batch *Batch, maxToRead int) error {
+	batch.len = 0
+	batch.columns = rows.Columns()
+	readers := make([]*columnReader, len(batch.columns))
+	for columnIndex, columnName := range batch.columns {
+		readers[columnIndex] = batch.newColumnReader(columnName, kindUnknown, maxToRead)
+	}
+	for batch.len < maxToRead {
+		err := rows.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		rowIndex := batch.len
+		for columnIndex, reader := range readers {
+			val := rows.row[columnIndex]
+			if reader.set(rowIndex, val) {
+				continue
+			}
+			reader = batch.promoteColumnReader(batch.columns[columnIndex], reader, val, rowIndex, maxToRead)
+			reader.set(rowIndex, val)
+			readers[columnIndex] = reader
+		}
+		batch.len++
+	}
+	if batch.len == 0 {
+		return io.EOF
+	}
+	return nil
+}
+
+// promoteColumnReader converts the rows already read to the type fitting both them and val
+func (batch *Batch) promoteColumnReader(columnName string, reader *columnReader, val driver.Value,
+	rowIndex int, maxToRead int) *columnReader {
+	kind := reader.kind
+	valKind := kindOf(val)
+	switch {
+	case kind == kindUnknown:
+		kind = valKind
+	case kind == kindTime || valKind == kindTime || valKind == kindString:
+		kind = kindString
+	case valKind > kind:
+		kind = valKind
+	default:
+		kind = kindString
+	}
+	promoted := batch.newColumnReader(columnName, kind, maxToRead)
+	for i := 0; i < rowIndex; i++ {
+		promoted.set(i, reader.get(i))
+	}
+	return promoted
+}
+
+type columnKind int
+
+const (
+	kindUnknown columnKind = iota
+	kindBool
+	kindInt64
+	kindFloat64
+	kindTime
+	kindString
+)
+
+func kindOf(val driver.Value) columnKind {
+	switch val.(type) {
+	case nil:
+		return kindUnknown
+	case bool:
+		return kindBool
+	case int64:
+		return kindInt64
+	case float64, float32:
+		return kindFloat64
+	case time.Time:
+		return kindTime
+	}
+	return kindString
+}
+
+// columnReader writes the values of one column into the typed column data of batch
+type columnReader struct {
+	kind columnKind
+	// []bool, []int64, []float64, []time.Time or []string, []string if kind is unknown
+	data  interface{}
+	nulls []bool
+}
+
+// newColumnReader reuses the column data of previous batch if the type and capacity fit
+func (batch *Batch) newColumnReader(columnName string, kind columnKind, maxToRead int) *columnReader {
+	nulls := batch.nulls[columnName]
+	if len(nulls) < maxToRead {
+		nulls = make([]bool, maxToRead)
+		batch.nulls[columnName] = nulls
+	}
+	data, found := batch.data[columnName]
+	if !found || dataKindOf(data) != dataKind(kind) || reflect.ValueOf(data).Len() < maxToRead {
+		data = makeColumnData(kind, maxToRead)
+		batch.data[columnName] = data
+	}
+	return &columnReader{kind, data, nulls}
+}
+
+// dataKind is the kind of column data, column with only NULL is string
+func dataKind(kind columnKind) columnKind {
+	if kind == kindUnknown {
+		return kindString
+	}
+	return kind
+}
+
+func dataKindOf(data interface{}) columnKind {
+	switch data.(type) {
+	case []bool:
+		return kindBool
+	case []int64:
+		return kindInt64
+	case []float64:
+		return kindFloat64
+	case []time.Time:
+		return kindTime
+	}
+	return kindString
+}
+
+func makeColumnData(kind columnKind, size int) interface{} {
+	switch dataKind(kind) {
+	case kindBool:
+		return make([]bool, size)
+	case kindInt64:
+		return make([]int64, size)
+	case kindFloat64:
+		return make([]float64, size)
+	case kindTime:
+		return make([]time.Time, size)
+	}
+	return make([]string, size)
+}
+
+// set returns false if the value does not fit the column type
+func (reader *columnReader) set(rowIndex int, val driver.Value) bool {
+	if val == nil {
+		reader.setZero(rowIndex)
+		reader.nulls[rowIndex] = true
+		return true
+	}
+	if reader.kind == kindUnknown {
+		return false
+	}
+	switch data := reader.data.(type) {
+	case []bool:
+		typedVal, ok := val.(bool)
+		if !ok {
+			return false
+		}
+		data[rowIndex] = typedVal
+	case []int64:
+		typedVal, ok := int64Of(val)
+		if !ok {
+			return false
+		}
+		data[rowIndex] = typedVal
+	case []float64:
+		typedVal, ok := float64Of(val)
+		if !ok {
+			return false
+		}
+		data[rowIndex] = typedVal
+	case []time.Time:
+		typedVal, ok := val.(time.Time)
+		if !ok {
+			return false
+		}
+		data[rowIndex] = typedVal
+	case []string:
+		// string() copies []byte, the driver reuses its buffer
+		data[rowIndex] = toStringValue(val)
+	}
+	reader.nulls[rowIndex] = false
+	return true
+}
+
+// get returns the value read, nil if NULL
+func (reader *columnReader) get(rowIndex int) driver.Value {
+	if reader.nulls[rowIndex] {
+		return nil
+	}
+	return reflect.ValueOf(reader.data).Index(rowIndex).Interface()
+}
+
+func (reader *columnReader) setZero(rowIndex int) {
+	switch data := reader.data.(type) {
+	case []bool:
+		data[rowIndex] = false
+	case []int64:
+		data[rowIndex] = 0
+	case []float64:
+		data[rowIndex] = 0
+	case []time.Time:
+		data[rowIndex] = time.Time{}
+	case []string:
+		data[rowIndex] = ""
+	}
+}
+
+func int64Of(val driver.Value) (int64, bool) {
+	switch typedVal := val.(type) {
+	case int64:
+		return typedVal, true
+	case bool:
+		if typedVal {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func float64Of(val driver.Value) (float64, bool) {
+	switch typedVal := val.(type) {
+	case float64:
+		return typedVal, true
+	case float32:
+		return float64(typedVal), true
+	}
+	int64Val, ok := int64Of(val)
+	return float64(int64Val), ok
+}
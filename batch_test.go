@@ -0,0 +1,114 @@
+package dingo
+
+import (
+	"database/sql/driver"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func Test_next_batch_types(t *testing.T) {
+	should := require.New(t)
+	now := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := queryFakeRows([]string{"entity_id", "version", "balance", "updated_at", "enabled", "comment", "id"},
+		[]driver.Value{[]byte("account1"), int64(1), float64(1.5), now, true, nil, uint64(18446744073709551615)},
+		[]driver.Value{"account2", nil, nil, nil, nil, nil, uint64(2)})
+	defer rows.Close()
+	batch := NewBatch()
+	should.Nil(rows.NextBatch(batch, 10))
+	should.Equal(2, batch.Len())
+	should.Equal([]string{"entity_id", "version", "balance", "updated_at", "enabled", "comment", "id"}, batch.Columns())
+	should.Equal([]string{"account1", "account2"}, batch.GetStringColumn("entity_id"))
+	should.Equal([]int64{1, 0}, batch.GetInt64Column("version"))
+	should.Equal([]float64{1.5, 0}, batch.GetFloat64Column("balance"))
+	should.Equal([]time.Time{now, {}}, batch.GetTimeColumn("updated_at"))
+	should.Equal([]bool{true, false}, batch.GetBoolColumn("enabled"))
+	should.Equal([]string{"18446744073709551615", "2"}, batch.GetStringColumn("id"))
+	should.False(batch.IsNull(0, "version"))
+	should.True(batch.IsNull(1, "version"))
+	should.True(batch.IsNull(1, "updated_at"))
+	should.True(batch.IsNull(0, "comment"))
+	should.Equal("", batch.GetString(0, "comment"))
+	should.False(batch.IsNull(0, "unknown"))
+	should.Equal(io.EOF, rows.NextBatch(batch, 10))
+	should.Equal(0, batch.Len())
+}
+
+func Test_next_batch_NULL_first(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"version"},
+		[]driver.Value{nil}, []driver.Value{int64(2)}, []driver.Value{[]byte("3")})
+	defer rows.Close()
+	batch := NewBatch()
+	should.Nil(rows.NextBatch(batch, 2))
+	should.Equal([]int64{0, 2}, batch.GetInt64Column("version"))
+	should.True(batch.IsNull(0, "version"))
+	should.Nil(rows.NextBatch(batch, 2))
+	should.Equal([]string{"3"}, batch.GetStringColumn("version"))
+}
+
+func Test_next_batch_until_EOF(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"version"},
+		[]driver.Value{int64(1)}, []driver.Value{int64(2)}, []driver.Value{int64(3)})
+	defer rows.Close()
+	batch := NewBatch()
+	should.Nil(rows.NextBatch(batch, 2))
+	should.Equal([]int64{1, 2}, batch.GetInt64Column("version"))
+	should.Nil(rows.NextBatch(batch, 2))
+	should.Equal([]int64{3}, batch.GetInt64Column("version"))
+	should.Equal(io.EOF, rows.NextBatch(batch, 2))
+	should.Equal(0, batch.Len())
+}
+
+func Test_next_batch_error(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"version"}, []driver.Value{int64(1)})
+	defer rows.Close()
+	// text of the error is part of "EOF", it is still an error
+	rows.obj.(*fakeRows).err = errors.New("E")
+	batch := NewBatch()
+	should.Equal("E", rows.NextBatch(batch, 2).Error())
+	should.Equal(1, batch.Len())
+}
+
+func Test_next_batch_promote(t *testing.T) {
+	should := require.New(t)
+	now := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := queryFakeRows([]string{"id", "amount", "flag", "created_at", "late"},
+		[]driver.Value{int64(1), int64(1), true, now, nil},
+		[]driver.Value{nil, float64(1.5), int64(2), now, nil},
+		[]driver.Value{[]byte("a3"), int64(3), nil, []byte("yesterday"), int64(4)})
+	defer rows.Close()
+	batch := NewBatch()
+	should.Nil(rows.NextBatch(batch, 10))
+	should.Equal(3, batch.Len())
+	should.Equal([]string{"1", "", "a3"}, batch.GetStringColumn("id"))
+	should.True(batch.IsNull(1, "id"))
+	should.Equal([]float64{1, 1.5, 3}, batch.GetFloat64Column("amount"))
+	should.Equal([]int64{1, 2, 0}, batch.GetInt64Column("flag"))
+	should.True(batch.IsNull(2, "flag"))
+	should.Equal([]string{"2018-01-02 03:04:05", "2018-01-02 03:04:05", "yesterday"}, batch.GetStringColumn("created_at"))
+	should.Equal([]int64{0, 0, 4}, batch.GetInt64Column("late"))
+	should.True(batch.IsNull(0, "late"))
+	should.False(batch.IsNull(2, "late"))
+}
+
+func Test_batch_column(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"version", "comment"},
+		[]driver.Value{int64(1), nil}, []driver.Value{nil, nil})
+	defer rows.Close()
+	batch := NewBatch()
+	should.Nil(rows.NextBatch(batch, 10))
+	should.Equal([]int64{1, 0}, batch.Column("version"))
+	should.Equal([]bool{false, true}, batch.NullColumn("version"))
+	should.Equal([]string{"", ""}, batch.Column("comment"))
+	should.Equal([]bool{true, true}, batch.NullColumn("comment"))
+	should.Equal(2, batch.Len())
+	should.Nil(batch.Column("unknown"))
+	should.Nil(batch.NullColumn("unknown"))
+	should.False(batch.IsNull(1, "unknown"))
+}
@@ -0,0 +1,196 @@
+package dingo
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+/*
+struct and map inputs are flattened into name value pairs before binding.
+struct field is bound by tag db:"entity_id", the field plan is cached per type
+*/
+
+type fieldPlan struct {
+	name  string
+	index []int
+}
+
+var fieldPlans = &sync.Map{}
+
+func getFieldPlans(typ reflect.Type) []fieldPlan {
+	plans, found := fieldPlans.Load(typ)
+	if found {
+		return plans.([]fieldPlan)
+	}
+	newPlans := buildFieldPlans(typ, nil)
+	fieldPlans.Store(typ, newPlans)
+	return newPlans
+}
+
+func buildFieldPlans(typ reflect.Type, parentIndex []int) []fieldPlan {
+	plans := []fieldPlan{}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		index := make([]int, 0, len(parentIndex)+1)
+		index = append(index, parentIndex...)
+		index = append(index, i)
+		tag := field.Tag.Get("db")
+		if tag == "-" {
+			continue
+		}
+		if tag == "" {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				plans = append(plans, buildFieldPlans(field.Type, index)...)
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		plans = append(plans, fieldPlan{name, index})
+	}
+	return plans
+}
+
+// flattenInputs expands struct, map and slice of struct inputs into name value pairs and batch insert rows
+func (stmt *Stmt) flattenInputs(inputs []driver.Value) ([]driver.Value, error) {
+	if !hasStructuredInput(inputs) {
+		return inputs, nil
+	}
+	flattened := make([]driver.Value, 0, len(inputs)*2)
+	for i := 0; i < len(inputs); i++ {
+		input := inputs[i]
+		if _, isName := input.(string); isName {
+			if i+1 < len(inputs) {
+				flattened = append(flattened, input, inputs[i+1])
+				i++
+				continue
+			}
+			flattened = append(flattened, input)
+			continue
+		}
+		if batchInsertRow, isBatchInsert := input.([]driver.Value); isBatchInsert {
+			row, err := stmt.flattenInputs(batchInsertRow)
+			if err != nil {
+				return nil, err
+			}
+			flattened = append(flattened, row)
+			continue
+		}
+		val := reflect.ValueOf(input)
+		if !isStructured(val) {
+			// not a name, let toArgs report it
+			flattened = append(flattened, input)
+			continue
+		}
+		if val.Kind() == reflect.Slice {
+			for j := 0; j < val.Len(); j++ {
+				row, err := stmt.flattenValue(val.Index(j), i)
+				if err != nil {
+					return nil, err
+				}
+				flattened = append(flattened, row)
+			}
+			continue
+		}
+		pairs, err := stmt.flattenValue(val, i)
+		if err != nil {
+			return nil, err
+		}
+		flattened = append(flattened, pairs...)
+	}
+	return flattened, nil
+}
+
+func hasStructuredInput(inputs []driver.Value) bool {
+	for i := 0; i < len(inputs); i++ {
+		switch inputs[i].(type) {
+		case string:
+			i++
+		case []driver.Value:
+			if hasStructuredInput(inputs[i].([]driver.Value)) {
+				return true
+			}
+		default:
+			if isStructured(reflect.ValueOf(inputs[i])) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isStructured tells struct, pointer to struct, map and slice of struct
+func isStructured(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Map, reflect.Struct:
+		return true
+	case reflect.Ptr:
+		return val.Type().Elem().Kind() == reflect.Struct
+	case reflect.Slice:
+		elemType := val.Type().Elem()
+		if elemType.Kind() == reflect.Ptr {
+			elemType = elemType.Elem()
+		}
+		return elemType.Kind() == reflect.Struct
+	}
+	return false
+}
+
+// flattenValue binds fields or map entries known by the sql, others are ignored like extra ROW columns
+func (stmt *Stmt) flattenValue(val reflect.Value, inputIndex int) ([]driver.Value, error) {
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil, stmt.bindError(ErrInvalidInputs, "", inputIndex)
+		}
+		val = val.Elem()
+	}
+	pairs := []driver.Value{}
+	switch val.Kind() {
+	case reflect.Struct:
+		for _, plan := range getFieldPlans(val.Type()) {
+			if _, found := stmt.translatedSql.paramMap[plan.name]; !found {
+				continue
+			}
+			fieldValue, err := stmt.convertFieldValue(plan.name, val.FieldByIndex(plan.index).Interface())
+			if err != nil {
+				return nil, stmt.bindError(ErrInvalidInputs, plan.name, inputIndex)
+			}
+			pairs = append(pairs, plan.name, fieldValue)
+		}
+	case reflect.Map:
+		if val.Type().Key().Kind() != reflect.String {
+			return nil, stmt.bindError(ErrInvalidInputs, "", inputIndex)
+		}
+		for _, key := range val.MapKeys() {
+			name := key.String()
+			if _, found := stmt.translatedSql.paramMap[name]; !found {
+				continue
+			}
+			entryValue, err := stmt.convertFieldValue(name, val.MapIndex(key).Interface())
+			if err != nil {
+				return nil, stmt.bindError(ErrInvalidInputs, name, inputIndex)
+			}
+			pairs = append(pairs, name, entryValue)
+		}
+	default:
+		return nil, stmt.bindError(ErrInvalidInputs, "", inputIndex)
+	}
+	return pairs, nil
+}
+
+// convertFieldValue converts field to driver value, except the values understood by Stmt itself
+func (stmt *Stmt) convertFieldValue(name string, fieldValue interface{}) (driver.Value, error) {
+	switch fieldValue.(type) {
+	case Identifier, Literal, Order, []Order, JSON:
+		return fieldValue, nil
+	}
+	if strings.HasPrefix(name, "IN_") || stmt.translatedSql.jsonParams[name] {
+		return fieldValue, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(fieldValue)
+}
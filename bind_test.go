@@ -0,0 +1,99 @@
+package dingo
+
+import (
+	"database/sql/driver"
+	"github.com/stretchr/testify/require"
+	"github.com/v2pro/plz/sql"
+	"testing"
+)
+
+type accountEvent struct {
+	EntityId  string `db:"entity_id"`
+	EventId   int32  `db:"event_id"`
+	EventName string `db:"event_name"`
+	Ignored   string `db:"-"`
+	accountState
+}
+
+type accountState struct {
+	State *string `db:"state"`
+}
+
+func Test_bind_struct(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{}
+	conn := openFakeConn(fake)
+	stmt := conn.TranslateStatement("UPDATE account SET :UPDATE_COLUMNS WHERE entity_id=:entity_id",
+		"event_id", "state")
+	defer stmt.Close()
+	state := "{}"
+	_, err := stmt.Exec(&accountEvent{EntityId: "account1", EventId: 2, accountState: accountState{&state}})
+	should.Nil(err)
+	should.Equal([]driver.Value{int64(2), "{}", "account1"}, fake.args)
+	_, err = stmt.Exec(accountEvent{EntityId: "account1", EventId: 3}, "PREPARED", true)
+	should.Nil(err)
+	should.Equal([]driver.Value{int64(3), nil, "account1"}, fake.args)
+	_, err = stmt.Exec(map[string]interface{}{"entity_id": "account2", "event_id": int64(4), "other": 1})
+	should.Nil(err)
+	should.Equal([]driver.Value{int64(4), nil, "account2"}, fake.args)
+	_, err = stmt.Exec(map[string]interface{}{"entity_id": "account3", "event_id": 5, "state": &state})
+	should.Nil(err)
+	should.Equal([]driver.Value{int64(5), "{}", "account3"}, fake.args)
+	_, err = stmt.Exec(map[string]interface{}{"entity_id": "account3", "event_id": struct{}{}})
+	should.Equal(ErrInvalidInputs, err.(*BindError).Cause)
+	should.Equal("event_id", err.(*BindError).ArgName)
+}
+
+func Test_bind_slice_of_struct(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{}
+	conn := openFakeConn(fake)
+	stmt := conn.TranslateStatement("INSERT account :BATCH_INSERT_COLUMNS",
+		sql.ColumnGroup{Group: "COLUMNS", Columns: []string{"entity_id", "event_id", "event_name"}, BatchInsertRowsCount: 2})
+	defer stmt.Close()
+	_, err := stmt.Exec([]accountEvent{
+		{EntityId: "account1", EventId: 1, EventName: "created"},
+		{EntityId: "account1", EventId: 2, EventName: "bill1_transfer"},
+	})
+	should.Nil(err)
+	should.Equal([]driver.Value{"account1", int64(1), "created", "account1", int64(2), "bill1_transfer"}, fake.args)
+	_, err = stmt.Exec(
+		BatchInsertRow(&accountEvent{EntityId: "account2", EventId: 1, EventName: "created"}),
+		BatchInsertRow("entity_id", "account2", "event_id", int64(2), "event_name", "bill1_transfer"))
+	should.Nil(err)
+	should.Equal([]driver.Value{"account2", int64(1), "created", "account2", int64(2), "bill1_transfer"}, fake.args)
+}
+
+func Test_bind_batch_insert_after_bound_column(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{}
+	conn := openFakeConn(fake)
+	stmt := conn.TranslateStatement(
+		"WITH deleted AS (DELETE FROM account WHERE entity_id=:entity_id) INSERT INTO account :BATCH_INSERT_COLUMNS",
+		Postgres, sql.ColumnGroup{Group: "COLUMNS", Columns: []string{"entity_id", "event_id"}, BatchInsertRowsCount: 2})
+	defer stmt.Close()
+	should.Equal("WITH deleted AS (DELETE FROM account WHERE entity_id=$1) INSERT INTO account (entity_id, event_id) VALUES ($2, $3), ($4, $5)",
+		stmt.(*Stmt).translatedSql.sql)
+	_, err := stmt.Exec(
+		BatchInsertRow("entity_id", "account1", "event_id", int64(1)),
+		BatchInsertRow("entity_id", "account1", "event_id", int64(2)))
+	should.Nil(err)
+	should.Equal([]driver.Value{"account1", "account1", int64(1), "account1", int64(2)}, fake.args)
+	_, err = stmt.Exec(BatchInsertRow("entity_id", "account1", "event_id", int64(1)))
+	should.Equal(ErrInvalidInputs, err.(*BindError).Cause)
+}
+
+func Test_bind_JSON_field(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{}
+	conn := openFakeConn(fake)
+	stmt := conn.TranslateStatement("UPDATE account SET :UPDATE_COLUMNS WHERE entity_id=:entity_id",
+		JSONColumns("COLUMNS", []string{"state"}, "state"))
+	defer stmt.Close()
+	_, err := stmt.Exec(struct {
+		EntityId string         `db:"entity_id"`
+		State    map[string]int `db:"state"`
+	}{"account1", map[string]int{"balance": 1}})
+	should.Nil(err)
+	should.Equal([]driver.Value{[]byte(`{"balance":1}`), "account1"}, fake.args)
+}
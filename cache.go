@@ -0,0 +1,161 @@
+package dingo
+
+import (
+	"bytes"
+	"container/list"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"github.com/v2pro/plz/sql"
+)
+
+/*
+translated sql cached by the sql template and the column arguments,
+least recently used entry is evicted when the cache is full
+*/
+
+const DefaultTranslationCacheSize = 1024
+
+type TranslationCacheStats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Size      int
+}
+
+type translationCache struct {
+	lock      sync.Mutex
+	maxSize   int
+	entries   map[string]*list.Element
+	lru       *list.List
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+type translationCacheEntry struct {
+	key           string
+	translatedSql *TranslatedSql
+}
+
+var globalTranslationCache = newTranslationCache(DefaultTranslationCacheSize)
+
+func newTranslationCache(maxSize int) *translationCache {
+	return &translationCache{maxSize: maxSize, entries: map[string]*list.Element{}, lru: list.New()}
+}
+
+// SetTranslationCacheSize changes the max entries of translation cache, 0 disables the cache
+func SetTranslationCacheSize(maxSize int) {
+	cache := globalTranslationCache
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.maxSize = maxSize
+	cache.evict()
+}
+
+func GetTranslationCacheStats() TranslationCacheStats {
+	cache := globalTranslationCache
+	cache.lock.Lock()
+	size := cache.lru.Len()
+	cache.lock.Unlock()
+	return TranslationCacheStats{
+		atomic.LoadInt64(&cache.hits),
+		atomic.LoadInt64(&cache.misses),
+		atomic.LoadInt64(&cache.evictions),
+		size,
+	}
+}
+
+// TranslateCached is TranslateE sharing the same *TranslatedSql for the same sql and columns
+func TranslateCached(sql string, columns ...interface{}) (*TranslatedSql, error) {
+	return globalTranslationCache.translate(sql, columns)
+}
+
+func (cache *translationCache) translate(sql string, columns []interface{}) (*TranslatedSql, error) {
+	key, cacheable := translationCacheKey(sql, columns)
+	if !cacheable {
+		return TranslateE(sql, columns...)
+	}
+	cache.lock.Lock()
+	elem := cache.entries[key]
+	if elem != nil {
+		cache.lru.MoveToFront(elem)
+		cache.lock.Unlock()
+		atomic.AddInt64(&cache.hits, 1)
+		return elem.Value.(*translationCacheEntry).translatedSql, nil
+	}
+	cache.lock.Unlock()
+	atomic.AddInt64(&cache.misses, 1)
+	translatedSql, err := TranslateE(sql, columns...)
+	if err != nil {
+		return nil, err
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	elem = cache.entries[key]
+	if elem != nil {
+		// translated concurrently, keep the first one
+		return elem.Value.(*translationCacheEntry).translatedSql, nil
+	}
+	if cache.maxSize <= 0 {
+		return translatedSql, nil
+	}
+	cache.entries[key] = cache.lru.PushFront(&translationCacheEntry{key, translatedSql})
+	cache.evict()
+	return translatedSql, nil
+}
+
+func (cache *translationCache) evict() {
+	for cache.lru.Len() > cache.maxSize && cache.lru.Len() > 0 {
+		oldest := cache.lru.Back()
+		cache.lru.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*translationCacheEntry).key)
+		atomic.AddInt64(&cache.evictions, 1)
+	}
+}
+
+// translationCacheKey is not cacheable if any column argument is not known
+func translationCacheKey(template string, columns []interface{}) (string, bool) {
+	key := bytes.NewBuffer(make([]byte, 0, len(template)+16*len(columns)))
+	key.WriteString(template)
+	for _, column := range columns {
+		key.WriteByte(0)
+		switch typed := column.(type) {
+		case string:
+			key.WriteByte('c')
+			key.WriteString(typed)
+		case Dialect:
+			key.WriteByte('d')
+			key.WriteString(strconv.Itoa(int(typed)))
+		case sql.ColumnGroup:
+			key.WriteByte('g')
+			writeColumnGroupKey(key, typed, nil, nil)
+		case ColumnGroup:
+			key.WriteByte('G')
+			writeColumnGroupKey(key, typed.ColumnGroup, typed.Keys, typed.JSONColumns)
+		default:
+			return "", false
+		}
+	}
+	return key.String(), true
+}
+
+func writeColumnGroupKey(key *bytes.Buffer, group sql.ColumnGroup, keys []string, jsonColumns []string) {
+	key.WriteString(group.Group)
+	key.WriteByte(1)
+	key.WriteString(strconv.Itoa(group.BatchInsertRowsCount))
+	for _, column := range group.Columns {
+		key.WriteByte(1)
+		key.WriteString(column)
+	}
+	key.WriteByte(2)
+	for _, column := range keys {
+		key.WriteByte(1)
+		key.WriteString(column)
+	}
+	key.WriteByte(3)
+	for _, column := range jsonColumns {
+		key.WriteByte(1)
+		key.WriteString(column)
+	}
+}
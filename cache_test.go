@@ -0,0 +1,62 @@
+package dingo
+
+import (
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+)
+
+func Test_translation_cache(t *testing.T) {
+	should := require.New(t)
+	cache := newTranslationCache(2)
+	translated1, err := cache.translate("SELECT :SELECT_COLUMNS FROM account WHERE entity_id=:entity_id",
+		[]interface{}{"entity_id", "state"})
+	should.Nil(err)
+	translated2, err := cache.translate("SELECT :SELECT_COLUMNS FROM account WHERE entity_id=:entity_id",
+		[]interface{}{"entity_id", "state"})
+	should.Nil(err)
+	should.True(translated1 == translated2)
+	translated3, err := cache.translate("SELECT :SELECT_COLUMNS FROM account WHERE entity_id=:entity_id",
+		[]interface{}{"entity_id", "state", Postgres})
+	should.Nil(err)
+	should.False(translated1 == translated3)
+	_, err = cache.translate("SELECT 1", nil)
+	should.Nil(err)
+	should.Equal(int64(1), cache.hits)
+	should.Equal(int64(3), cache.misses)
+	should.Equal(int64(1), cache.evictions)
+	should.Equal(2, cache.lru.Len())
+	_, err = cache.translate("SELECT :Pid", nil)
+	should.NotNil(err)
+}
+
+func Test_translation_cache_concurrently(t *testing.T) {
+	should := require.New(t)
+	cache := newTranslationCache(8)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cache.translate("INSERT account :INSERT_COLUMNS", []interface{}{"entity_id", "state"})
+			}
+		}()
+	}
+	wg.Wait()
+	should.Equal(int64(1600), cache.hits+cache.misses)
+	should.Equal(1, cache.lru.Len())
+}
+
+func Test_translation_cache_JSON_columns(t *testing.T) {
+	should := require.New(t)
+	cache := newTranslationCache(2)
+	group := UpsertColumns("COLUMNS", nil, "entity_id", "state")
+	plain, err := cache.translate("INSERT account :INSERT_COLUMNS", []interface{}{group})
+	should.Nil(err)
+	marked, err := cache.translate("INSERT account :INSERT_COLUMNS", []interface{}{group.WithJSON("state")})
+	should.Nil(err)
+	should.False(plain == marked)
+	should.True(marked.jsonParams["state"])
+	should.False(plain.jsonParams["state"])
+}
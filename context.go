@@ -0,0 +1,156 @@
+package dingo
+
+import (
+	"context"
+	"database/sql/driver"
+	"sync/atomic"
+)
+
+/*
+context is passed to the driver if it implements the context interfaces,
+otherwise the legacy call is watched, the conn is closed to abort the call when context is done.
+the conn closed by watchdog has Error set, pool does not reuse it.
+context done before the legacy call starts is returned without calling the driver
+*/
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+const (
+	watchRunning int32 = iota
+	watchFinished
+	watchAborted
+)
+
+// watch starts the watchdog of a legacy call, ctx.Err() if it is done already.
+// the returned func stops it with the error of the call, the call is aborted
+// only if the watchdog closed the conn before the call finished
+func (conn *Conn) watch(ctx context.Context) (func(error) error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		return func(callErr error) error {
+			return callErr
+		}, nil
+	}
+	state := watchRunning
+	finished := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			// select might pick ctx.Done() after the call finished
+			if atomic.CompareAndSwapInt32(&state, watchRunning, watchAborted) {
+				conn.closeObj()
+			}
+		case <-finished:
+		}
+	}()
+	return func(callErr error) error {
+		if atomic.CompareAndSwapInt32(&state, watchRunning, watchFinished) {
+			close(finished)
+			return callErr
+		}
+		<-stopped
+		conn.Error = ctx.Err()
+		if callErr == nil {
+			// finished while the conn is being closed, the result is kept
+			return nil
+		}
+		return ctx.Err()
+	}, nil
+}
+
+func (conn *Conn) prepareContext(ctx context.Context, formattedSql string) (driver.Stmt, error) {
+	if preparer, isPreparer := conn.obj.(driver.ConnPrepareContext); isPreparer {
+		return preparer.PrepareContext(ctx, formattedSql)
+	}
+	stopWatch, err := conn.watch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := conn.obj.Prepare(formattedSql)
+	if err = stopWatch(err); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func (conn *Conn) execStmtContext(ctx context.Context, obj driver.Stmt, args []driver.Value) (driver.Result, error) {
+	if execer, isExecer := obj.(driver.StmtExecContext); isExecer {
+		return execer.ExecContext(ctx, namedValues(args))
+	}
+	stopWatch, err := conn.watch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := obj.Exec(args)
+	if err = stopWatch(err); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (conn *Conn) queryStmtContext(ctx context.Context, obj driver.Stmt, args []driver.Value) (driver.Rows, error) {
+	if queryer, isQueryer := obj.(driver.StmtQueryContext); isQueryer {
+		return queryer.QueryContext(ctx, namedValues(args))
+	}
+	stopWatch, err := conn.watch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := obj.Query(args)
+	if err = stopWatch(err); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// execContext executes without prepare, false if the driver does not support it
+func (conn *Conn) execContext(ctx context.Context, formattedSql string, args []driver.Value) (driver.Result, bool, error) {
+	if execer, isExecer := conn.obj.(driver.ExecerContext); isExecer {
+		result, err := execer.ExecContext(ctx, formattedSql, namedValues(args))
+		return result, true, err
+	}
+	execer, isExecer := conn.obj.(driver.Execer)
+	if !isExecer {
+		return nil, false, nil
+	}
+	stopWatch, err := conn.watch(ctx)
+	if err != nil {
+		return nil, true, err
+	}
+	result, err := execer.Exec(formattedSql, args)
+	if err = stopWatch(err); err != nil {
+		return nil, true, err
+	}
+	return result, true, nil
+}
+
+// queryContext queries without prepare, false if the driver does not support it
+func (conn *Conn) queryContext(ctx context.Context, formattedSql string, args []driver.Value) (driver.Rows, bool, error) {
+	if queryer, isQueryer := conn.obj.(driver.QueryerContext); isQueryer {
+		rows, err := queryer.QueryContext(ctx, formattedSql, namedValues(args))
+		return rows, true, err
+	}
+	queryer, isQueryer := conn.obj.(driver.Queryer)
+	if !isQueryer {
+		return nil, false, nil
+	}
+	stopWatch, err := conn.watch(ctx)
+	if err != nil {
+		return nil, true, err
+	}
+	rows, err := queryer.Query(formattedSql, args)
+	if err = stopWatch(err); err != nil {
+		return nil, true, err
+	}
+	return rows, true, nil
+}
@@ -0,0 +1,179 @@
+package dingo
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_exec_context(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeContextConn{}
+	conn, _ := Open(&fakeContextDriver{fake}, "fake")
+	stmt := conn.TranslateStatement("DELETE FROM account WHERE entity_id=:entity_id").(*Stmt)
+	defer stmt.Close()
+	ctx := context.WithValue(context.Background(), traceKey{}, "trace1")
+	_, err := stmt.ExecContext(ctx, "entity_id", "account1")
+	should.Nil(err)
+	should.Equal("trace1", fake.ctx.Value(traceKey{}))
+	should.Equal([]driver.NamedValue{{Ordinal: 1, Value: "account1"}}, fake.args)
+	_, err = stmt.QueryContext(ctx, "entity_id", "account2", "PREPARED", false)
+	should.Nil(err)
+	should.Equal([]driver.NamedValue{{Ordinal: 1, Value: "account2"}}, fake.args)
+}
+
+func Test_exec_context_watchdog(t *testing.T) {
+	should := require.New(t)
+	slow := &slowConn{closed: make(chan struct{})}
+	conn, _ := Open(&slowDriver{slow}, "slow")
+	stmt := conn.TranslateStatement("SELECT SLEEP(:seconds)").(*Stmt)
+	defer stmt.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := stmt.QueryContext(ctx, "seconds", int64(60))
+	should.True(errors.Is(err, context.DeadlineExceeded))
+	should.NotNil(conn.(*Conn).Error)
+	_, stillOpen := <-slow.closed
+	should.False(stillOpen)
+	should.Nil(conn.Close())
+	should.Equal(int32(1), atomic.LoadInt32(&slow.closeCount))
+	// not watched without deadline
+	fast := &fakeConn{}
+	_, err = openFakeConn(fast).TranslateStatement("SELECT 1").(*Stmt).ExecContext(context.Background())
+	should.Nil(err)
+}
+
+func Test_exec_context_done_before_call(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{}
+	conn := openFakeConn(fake)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := conn.TranslateStatement("SELECT 1").(*Stmt).ExecContext(ctx)
+	should.True(errors.Is(err, context.Canceled))
+	should.Equal(0, len(fake.prepared))
+	should.Nil(conn.Error)
+}
+
+type traceKey struct{}
+
+type fakeContextDriver struct {
+	conn *fakeContextConn
+}
+
+func (drv *fakeContextDriver) Open(dsn string) (driver.Conn, error) {
+	return drv.conn, nil
+}
+
+// fakeContextConn only implements the context interfaces for exec and query
+type fakeContextConn struct {
+	ctx  context.Context
+	args []driver.NamedValue
+}
+
+func (conn *fakeContextConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("should use PrepareContext")
+}
+
+func (conn *fakeContextConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	conn.ctx = ctx
+	return &fakeContextStmt{conn}, nil
+}
+
+func (conn *fakeContextConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	conn.ctx = ctx
+	conn.args = args
+	return &fakeRows{}, nil
+}
+
+func (conn *fakeContextConn) Close() error {
+	return nil
+}
+
+func (conn *fakeContextConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+type fakeContextStmt struct {
+	conn *fakeContextConn
+}
+
+func (stmt *fakeContextStmt) Close() error {
+	return nil
+}
+
+func (stmt *fakeContextStmt) NumInput() int {
+	return -1
+}
+
+func (stmt *fakeContextStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("should use ExecContext")
+}
+
+func (stmt *fakeContextStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	stmt.conn.ctx = ctx
+	stmt.conn.args = args
+	return driver.RowsAffected(1), nil
+}
+
+func (stmt *fakeContextStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("should use QueryContext")
+}
+
+type slowDriver struct {
+	conn *slowConn
+}
+
+func (drv *slowDriver) Open(dsn string) (driver.Conn, error) {
+	return drv.conn, nil
+}
+
+// slowConn blocks every statement until it is closed
+type slowConn struct {
+	closeOnce  sync.Once
+	closed     chan struct{}
+	closeCount int32
+}
+
+func (conn *slowConn) Prepare(query string) (driver.Stmt, error) {
+	return &slowStmt{conn}, nil
+}
+
+func (conn *slowConn) Close() error {
+	atomic.AddInt32(&conn.closeCount, 1)
+	conn.closeOnce.Do(func() {
+		close(conn.closed)
+	})
+	return nil
+}
+
+func (conn *slowConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+type slowStmt struct {
+	conn *slowConn
+}
+
+func (stmt *slowStmt) Close() error {
+	return nil
+}
+
+func (stmt *slowStmt) NumInput() int {
+	return -1
+}
+
+func (stmt *slowStmt) Exec(args []driver.Value) (driver.Result, error) {
+	<-stmt.conn.closed
+	return nil, driver.ErrBadConn
+}
+
+func (stmt *slowStmt) Query(args []driver.Value) (driver.Rows, error) {
+	<-stmt.conn.closed
+	return nil, driver.ErrBadConn
+}
@@ -0,0 +1,92 @@
+package dingo
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+dialect decides how normal argument is rendered in translated sql.
+pass it to Translate together with the columns, Translate(sql, Postgres, "a", "b")
+*/
+
+type Dialect int
+
+const (
+	MySQL    Dialect = 0 // ?
+	Postgres Dialect = 1 // $1, $2, same name share one number
+)
+
+func (dialect Dialect) String() string {
+	switch dialect {
+	case Postgres:
+		return "Postgres"
+	}
+	return "MySQL"
+}
+
+// writePlaceholder renders the normal argument at pos (0 based, excluding string arguments)
+func (dialect Dialect) writePlaceholder(buf *bytes.Buffer, pos int) {
+	if dialect == Postgres {
+		buf.WriteByte('$')
+		buf.WriteString(strconv.Itoa(pos + 1))
+		return
+	}
+	buf.WriteByte('?')
+}
+
+// Tuple renders the values as literal list (a,b,c), string is quoted and escaped for the dialect.
+// empty list renders (NULL), so that IN (NULL) matches nothing
+func (dialect Dialect) Tuple(vals ...interface{}) Literal {
+	if len(vals) == 0 {
+		return Literal("(NULL)")
+	}
+	parts := make([]string, len(vals))
+	for i := 0; i < len(vals); i++ {
+		parts[i] = dialect.literal(vals[i])
+	}
+	return Literal("(" + strings.Join(parts, ",") + ")")
+}
+
+func (dialect Dialect) literal(val interface{}) string {
+	switch typedVal := val.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return dialect.quote(typedVal)
+	case []byte:
+		if dialect == Postgres {
+			return `'\x` + hex.EncodeToString(typedVal) + `'::bytea`
+		}
+		return "X'" + hex.EncodeToString(typedVal) + "'"
+	case time.Time:
+		return "'" + typedVal.Format("2006-01-02 15:04:05.999999") + "'"
+	case bool:
+		if typedVal {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprintf("%v", typedVal)
+	case Literal:
+		return string(typedVal)
+	}
+	return dialect.quote(fmt.Sprintf("%v", val))
+}
+
+// quote doubles the single quote, which does not depend on sql_mode or standard_conforming_strings.
+// text with backslash is written as X'hex' for mysql, and E'' with backslash doubled for postgres
+func (dialect Dialect) quote(val string) string {
+	quoted := strings.Replace(val, "'", "''", -1)
+	if !strings.Contains(val, `\`) {
+		return "'" + quoted + "'"
+	}
+	if dialect == Postgres {
+		return "E'" + strings.Replace(quoted, `\`, `\\`, -1) + "'"
+	}
+	return "X'" + hex.EncodeToString([]byte(val)) + "'"
+}
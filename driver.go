@@ -1,10 +1,15 @@
 package dingo
 
 import (
+	"context"
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"math"
 	"os"
+	"reflect"
 	"strings"
+	"sync/atomic"
 	"time"
 	"github.com/v2pro/plz/sql"
 )
@@ -13,17 +18,56 @@ func Join(columns ...string) string {
 	return strings.Join(columns, ", ")
 }
 
+// Tuple renders the values as mysql literal list, use Postgres.Tuple for postgres
 func Tuple(vals ...interface{}) string {
-	parts := make([]string, len(vals))
-	for i := 0; i < len(vals); i++ {
-		switch typedVal := vals[i].(type) {
-		case string:
-			parts[i] = "'" + typedVal + "'"
-		default:
-			parts[i] = fmt.Sprintf("%v", vals[i])
+	return string(MySQL.Tuple(vals...))
+}
+
+// TupleLiteral is Tuple passed as STR_ argument, Stmt.Exec("STR_ids", TupleLiteral("a", "b"))
+func TupleLiteral(vals ...interface{}) Literal {
+	return MySQL.Tuple(vals...)
+}
+
+// Identifier is table or column name, checked before formatted into sql as STR_ argument
+type Identifier string
+
+// Literal is sql fragment formatted into sql as it is, only use it for trusted text
+type Literal string
+
+// JSON is marshaled when bound, Stmt.Exec("state", JSON{state})
+type JSON struct {
+	Value interface{}
+}
+
+// Order is the value of ORDER_BY_ argument, the column must be in the column group
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+func Asc(column string) Order {
+	return Order{column, false}
+}
+
+func Desc(column string) Order {
+	return Order{column, true}
+}
+
+func isValidIdentifier(identifier string) bool {
+	if len(identifier) == 0 {
+		return false
+	}
+	for i := 0; i < len(identifier); i++ {
+		c := identifier[i]
+		if c == '.' && i != 0 && i != len(identifier)-1 && identifier[i-1] != '.' {
+			// schema.table
+			continue
+		}
+		if !(('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c == '_' || c == '$' || ('0' <= c && c <= '9')) {
+			return false
 		}
 	}
-	return "(" + strings.Join(parts, ",") + ")"
+	return true
 }
 
 type Conn struct {
@@ -33,6 +77,12 @@ type Conn struct {
 	activeQueryArgs []driver.Value
 	Error           error
 	onClose         func(conn *Conn) error
+	strict          bool
+	location        *time.Location
+	// default of the pool, used if location is not set
+	poolLocation *time.Location
+	// set by closeObj, obj might be closed by watchdog before Close
+	objClosed int32
 }
 
 func Open(drv driver.Driver, dsn string) (sql.Conn, error) {
@@ -40,16 +90,39 @@ func Open(drv driver.Driver, dsn string) (sql.Conn, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Conn{conn, nil, "", nil, nil, nil}, nil
+	return &Conn{conn, nil, "", nil, nil, nil, false, nil, nil, 0}, nil
 }
 
 func (conn *Conn) TranslateStatement(sql string, columns ...interface{}) sql.Stmt {
-	translatedSql := Translate(sql, columns...)
-	return &Stmt{conn, map[string]driver.Stmt{}, translatedSql.(*TranslatedSql)}
+	translatedSql, err := TranslateCached(sql, columns...)
+	if err != nil {
+		panic(err)
+	}
+	return &Stmt{conn, map[string]driver.Stmt{}, translatedSql, nil, false}
 }
 
 func (conn *Conn) Statement(translatedSql sql.Translated) sql.Stmt {
-	return &Stmt{conn, map[string]driver.Stmt{}, translatedSql.(*TranslatedSql)}
+	return &Stmt{conn, map[string]driver.Stmt{}, translatedSql.(*TranslatedSql), nil, false}
+}
+
+// SetStrict makes every statement of the connection fail on unbound argument
+func (conn *Conn) SetStrict(strict bool) {
+	conn.strict = strict
+}
+
+// SetLocation changes the location to read time text without zone until the conn returns to its pool, nil means the location of the pool or DefaultLocation
+func (conn *Conn) SetLocation(location *time.Location) {
+	conn.location = location
+}
+
+func (conn *Conn) Location() *time.Location {
+	if conn.location != nil {
+		return conn.location
+	}
+	if conn.poolLocation != nil {
+		return conn.poolLocation
+	}
+	return DefaultLocation
 }
 
 func (conn *Conn) Close() error {
@@ -57,12 +130,20 @@ func (conn *Conn) Close() error {
 		return nil
 	}
 	if conn.onClose == nil {
-		return conn.obj.Close()
+		return conn.closeObj()
 	} else {
 		return conn.onClose(conn)
 	}
 }
 
+// closeObj closes the driver conn only once
+func (conn *Conn) closeObj() error {
+	if !atomic.CompareAndSwapInt32(&conn.objClosed, 0, 1) {
+		return nil
+	}
+	return conn.obj.Close()
+}
+
 func (conn *Conn) BeginTx() error {
 	tx, err := conn.obj.Begin()
 	if err != nil {
@@ -81,15 +162,27 @@ func (conn *Conn) RollbackTx() error {
 }
 
 func (conn *Conn) Exec(translatedSql *TranslatedSql, inputs ...driver.Value) (driver.Result, error) {
-	stmt := conn.Statement(translatedSql)
+	return conn.ExecContext(context.Background(), translatedSql, inputs...)
+}
+
+func (conn *Conn) ExecContext(ctx context.Context, translatedSql *TranslatedSql, inputs ...driver.Value) (driver.Result, error) {
+	stmt := conn.Statement(translatedSql).(*Stmt)
 	defer stmt.Close()
-	return stmt.Exec(inputs...)
+	return stmt.ExecContext(ctx, inputs...)
 }
 
 type Stmt struct {
 	conn          *Conn
 	objs          map[string]driver.Stmt
 	translatedSql *TranslatedSql
+	// bindShape key => statement sharing the objs, translated for the shape
+	expanded map[string]*Stmt
+	strict   bool
+}
+
+// SetStrict makes Exec and Query fail on unbound argument and unused ROW column
+func (stmt *Stmt) SetStrict(strict bool) {
+	stmt.strict = strict
 }
 
 func (stmt *Stmt) Close() error {
@@ -108,53 +201,114 @@ func BatchInsertRow(inputs ...driver.Value) []driver.Value {
 }
 
 func (stmt *Stmt) Exec(inputs ...driver.Value) (driver.Result, error) {
-	args, prepared := stmt.toArgs(inputs)
-	formattedSql := stmt.format(args)
+	return stmt.ExecContext(context.Background(), inputs...)
+}
+
+// ExecContext aborts the exec when ctx is done, see context.go for drivers without context support
+func (stmt *Stmt) ExecContext(ctx context.Context, inputs ...driver.Value) (driver.Result, error) {
+	// the conn is not used yet, so it is not marked as broken
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	inputs, err := stmt.flattenInputs(inputs)
+	if err != nil {
+		return nil, err
+	}
+	strict := stmt.strict || stmt.conn.strict
+	stmt, err = stmt.expand(inputs)
+	if err != nil {
+		return nil, err
+	}
+	if strict {
+		if err := stmt.checkBound(inputs); err != nil {
+			return nil, err
+		}
+	}
+	args, prepared, err := stmt.toArgs(inputs)
+	if err != nil {
+		return nil, err
+	}
+	formattedSql, err := stmt.format(args)
+	if err != nil {
+		return nil, err
+	}
 	execArgs := args[stmt.translatedSql.strParamCount:]
 	var result driver.Result
-	var err error
 	var obj driver.Stmt
 	if prepared {
-		obj, err = stmt.prepare(formattedSql)
+		obj, err = stmt.prepare(ctx, formattedSql)
 		if err != nil {
-			return nil, fmt.Errorf("%s\nsql: %v\n", err.Error(), formattedSql)
+			return nil, fmt.Errorf("%w\nsql: %v\n", err, formattedSql)
 		}
-		result, err = obj.Exec(execArgs)
+		result, err = stmt.conn.execStmtContext(ctx, obj, execArgs)
 	} else {
-		execer := stmt.conn.obj.(driver.Execer)
-		result, err = execer.Exec(formattedSql, execArgs)
+		var isExecer bool
+		result, isExecer, err = stmt.conn.execContext(ctx, formattedSql, execArgs)
+		if !isExecer {
+			return nil, fmt.Errorf("driver does not support exec without prepare\nsql: %v\n", formattedSql)
+		}
 	}
 	if err != nil {
 		stmt.conn.Error = err
-		return nil, fmt.Errorf("%s\nsql: %v\nargs: %v", err.Error(), formattedSql, execArgs)
+		return nil, fmt.Errorf("%w\nsql: %v\nargs: %v", err, formattedSql, execArgs)
 	}
 	return result, err
 }
 
 func (stmt *Stmt) Query(inputs ...driver.Value) (sql.Rows, error) {
+	return stmt.QueryContext(context.Background(), inputs...)
+}
+
+// QueryContext aborts the query when ctx is done, reading the rows is not watched
+func (stmt *Stmt) QueryContext(ctx context.Context, inputs ...driver.Value) (sql.Rows, error) {
 	if stmt.conn.activeQuerySql != "" {
 		return nil, fmt.Errorf("there is another active query in progress\nsql: %v\nargs: %v",
 			stmt.conn.activeQuerySql, stmt.conn.activeQueryArgs)
 	}
-	args, prepared := stmt.toArgs(inputs)
-	formattedSql := stmt.format(args)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	inputs, err := stmt.flattenInputs(inputs)
+	if err != nil {
+		return nil, err
+	}
+	strict := stmt.strict || stmt.conn.strict
+	stmt, err = stmt.expand(inputs)
+	if err != nil {
+		return nil, err
+	}
+	if strict {
+		if err := stmt.checkBound(inputs); err != nil {
+			return nil, err
+		}
+	}
+	args, prepared, err := stmt.toArgs(inputs)
+	if err != nil {
+		return nil, err
+	}
+	formattedSql, err := stmt.format(args)
+	if err != nil {
+		return nil, err
+	}
 	queryArgs := args[stmt.translatedSql.strParamCount:]
 	var rows driver.Rows
-	var err error
 	var obj driver.Stmt
 	if prepared {
-		obj, err = stmt.prepare(formattedSql)
+		obj, err = stmt.prepare(ctx, formattedSql)
 		if err != nil {
-			return nil, fmt.Errorf("%s\nsql: %v\n", err.Error(), formattedSql)
+			return nil, fmt.Errorf("%w\nsql: %v\n", err, formattedSql)
 		}
-		rows, err = obj.Query(queryArgs)
+		rows, err = stmt.conn.queryStmtContext(ctx, obj, queryArgs)
 	} else {
-		queryer := stmt.conn.obj.(driver.Queryer)
-		rows, err = queryer.Query(formattedSql, queryArgs)
+		var isQueryer bool
+		rows, isQueryer, err = stmt.conn.queryContext(ctx, formattedSql, queryArgs)
+		if !isQueryer {
+			return nil, fmt.Errorf("driver does not support query without prepare\nsql: %v\n", formattedSql)
+		}
 	}
 	if err != nil {
 		stmt.conn.Error = err
-		return nil, fmt.Errorf("%s\nsql: %v\nargs: %v", err.Error(), formattedSql, queryArgs)
+		return nil, fmt.Errorf("%w\nsql: %v\nargs: %v", err, formattedSql, queryArgs)
 	}
 	columns := map[string]sql.ColumnIndex{}
 	for idx, column := range rows.Columns() {
@@ -162,30 +316,61 @@ func (stmt *Stmt) Query(inputs ...driver.Value) (sql.Rows, error) {
 	}
 	stmt.conn.activeQuerySql = formattedSql
 	stmt.conn.activeQueryArgs = queryArgs
-	return &Rows{stmt.conn, rows, columns, make([]driver.Value, len(columns))}, nil
+	return &Rows{stmt.conn, rows, columns, make([]driver.Value, len(columns)), nil, nil}, nil
 }
 
-func (stmt *Stmt) toArgs(inputs []driver.Value) ([]driver.Value, bool) {
+func (stmt *Stmt) toArgs(inputs []driver.Value) ([]driver.Value, bool, error) {
 	if len(inputs) == 0 {
-		return []driver.Value{}, true
+		return []driver.Value{}, true, nil
 	}
 	_, isBatchInsert := inputs[0].([]driver.Value)
 	if isBatchInsert {
+		batchInsert := stmt.translatedSql.batchInsert
+		if batchInsert != nil && len(inputs) != batchInsert.rowsCount {
+			return nil, false, stmt.bindError(ErrInvalidInputs, "", len(inputs)-1)
+		}
 		args := make([]driver.Value, 0, 64)
-		for _, batchInsertRow := range inputs {
-			rowArgs, _ := stmt.toArgs(batchInsertRow.([]driver.Value))
-			args = append(args, rowArgs...)
+		for i, batchInsertRow := range inputs {
+			typedRow, isRow := batchInsertRow.([]driver.Value)
+			if !isRow {
+				return nil, false, stmt.bindError(ErrInvalidInputs, "", i)
+			}
+			rowArgs, _, err := stmt.toArgs(typedRow)
+			if err != nil {
+				return nil, false, err
+			}
+			if batchInsert == nil {
+				args = append(args, rowArgs...)
+				continue
+			}
+			// arguments out of the VALUES rows are bound by the first row
+			if i == 0 {
+				args = rowArgs
+				continue
+			}
+			rowStart := batchInsert.base + i*batchInsert.columnsCount
+			copy(args[rowStart:rowStart+batchInsert.columnsCount],
+				rowArgs[batchInsert.base:batchInsert.base+batchInsert.columnsCount])
 		}
-		return args, true
+		return args, true, nil
+	}
+	if len(inputs)%2 != 0 {
+		return nil, false, stmt.bindError(ErrInvalidInputs, "", len(inputs)-1)
 	}
 	prepared := true
 	args := make([]driver.Value, stmt.translatedSql.totalParamCount)
 	for i := 0; i < len(inputs); i += 2 {
-		argName := inputs[i].(string)
+		argName, isName := inputs[i].(string)
+		if !isName {
+			return nil, false, stmt.bindError(ErrInvalidInputs, fmt.Sprintf("%v", inputs[i]), i)
+		}
 		argValue := inputs[i+1]
 		switch argName {
 		case "ROW":
-			row := argValue.(*Rows)
+			row, isRows := argValue.(*Rows)
+			if !isRows {
+				return nil, false, stmt.bindError(ErrInvalidInputs, argName, i+1)
+			}
 			// bind row to args, if row has extra column, ignore
 			for column, columnIdx := range row.columns {
 				argIndices, found := stmt.translatedSql.paramMap[column]
@@ -196,26 +381,190 @@ func (stmt *Stmt) toArgs(inputs []driver.Value) ([]driver.Value, bool) {
 				}
 			}
 		case "PREPARED":
-			prepared = argValue.(bool)
+			typedPrepared, isBool := argValue.(bool)
+			if !isBool {
+				return nil, false, stmt.bindError(ErrInvalidInputs, argName, i+1)
+			}
+			prepared = typedPrepared
 		default:
 			argIndices, found := stmt.translatedSql.paramMap[argName]
 			if !found {
-				panic("argument not found in sql: " + argName)
+				return nil, false, stmt.bindError(ErrArgumentNotFound, argName, i)
+			}
+			if argName == "LIMIT" || argName == "OFFSET" {
+				count, isInt := toInt64(argValue)
+				if !isInt || count < 0 {
+					return nil, false, stmt.bindError(ErrInvalidInputs, argName, i+1)
+				}
+				argValue = count
+			}
+			if strings.HasPrefix(argName, "IN_") {
+				list := reflect.ValueOf(argValue)
+				if !isList(list) {
+					return nil, false, stmt.bindError(ErrInvalidInputs, argName, i+1)
+				}
+				// empty list is rendered as (NULL) by expand, batch insert row is not expanded
+				if list.Len() == 0 && len(argIndices) > 0 {
+					return nil, false, stmt.bindError(ErrEmptyList, argName, i+1)
+				}
+				// the same list might be referenced more than once
+				for j, argIdx := range argIndices {
+					args[argIdx] = list.Index(j % list.Len()).Interface()
+				}
+				continue
+			}
+			argValue, err := stmt.toJSONArg(argName, argValue)
+			if err != nil {
+				return nil, false, stmt.bindError(ErrInvalidInputs, argName, i+1)
 			}
 			for _, argIdx := range argIndices {
 				args[argIdx] = argValue
 			}
 		}
 	}
-	return args, prepared
+	return args, prepared, nil
 }
 
-func (stmt *Stmt) format(args []driver.Value) string {
+// toJSONArg marshals JSON value, and value of column marked as JSON unless it is text already
+func (stmt *Stmt) toJSONArg(argName string, argValue driver.Value) (driver.Value, error) {
+	if wrapped, isJSON := argValue.(JSON); isJSON {
+		return json.Marshal(wrapped.Value)
+	}
+	if !stmt.translatedSql.jsonParams[argName] {
+		return argValue, nil
+	}
+	switch argValue.(type) {
+	case nil, string, []byte:
+		return argValue, nil
+	}
+	return json.Marshal(argValue)
+}
+
+// checkBound reports every unbound argument and unused ROW column
+func (stmt *Stmt) checkBound(inputs []driver.Value) error {
+	unbound := stmt.translatedSql.Unbound(inputs...)
+	unusedColumns := []string{}
+	for i := 0; i+1 < len(inputs); i += 2 {
+		if inputs[i] != "ROW" {
+			continue
+		}
+		row, isRows := inputs[i+1].(*Rows)
+		if !isRows {
+			continue
+		}
+		for _, column := range row.Columns() {
+			if _, found := stmt.translatedSql.paramMap[column]; !found {
+				unusedColumns = append(unusedColumns, column)
+			}
+		}
+	}
+	if len(unbound) == 0 && len(unusedColumns) == 0 {
+		return nil
+	}
+	return &UnboundError{unbound, unusedColumns, stmt.translatedSql.sql}
+}
+
+// expand returns the statement translated for the shape of the inputs, IN_ list lengths, NULL columns and bound names
+func (stmt *Stmt) expand(inputs []driver.Value) (*Stmt, error) {
+	translatedSql := stmt.translatedSql
+	if len(translatedSql.shapeParams) == 0 {
+		return stmt, nil
+	}
+	if len(inputs) > 0 {
+		if _, isBatchInsert := inputs[0].([]driver.Value); isBatchInsert {
+			return stmt, nil
+		}
+	}
+	shape := bindShape{map[string]int{}, map[string]bool{}, map[string]bool{}}
+	for i := 0; i+1 < len(inputs); i += 2 {
+		argName, isName := inputs[i].(string)
+		if !isName {
+			continue
+		}
+		shape.boundParams[argName] = true
+		if argName == "ROW" {
+			if row, isRows := inputs[i+1].(*Rows); isRows {
+				for column := range row.columns {
+					shape.boundParams[column] = true
+				}
+			}
+		} else if strings.HasPrefix(argName, "IN_") {
+			list := reflect.ValueOf(inputs[i+1])
+			if !isList(list) {
+				return nil, stmt.bindError(ErrInvalidInputs, argName, i+1)
+			}
+			shape.listLengths[argName] = list.Len()
+		} else if inputs[i+1] == nil {
+			shape.nullColumns[argName] = true
+		}
+	}
+	key := shape.key(translatedSql.shapeParams)
+	expanded := stmt.expanded[key]
+	if expanded != nil {
+		return expanded, nil
+	}
+	columns := make([]interface{}, 0, len(translatedSql.columns)+1)
+	columns = append(columns, translatedSql.columns...)
+	columns = append(columns, shape)
+	expandedSql, err := TranslateE(translatedSql.template, columns...)
+	if err != nil {
+		return nil, err
+	}
+	if stmt.expanded == nil {
+		stmt.expanded = map[string]*Stmt{}
+	}
+	expanded = &Stmt{stmt.conn, stmt.objs, expandedSql, nil, false}
+	stmt.expanded[key] = expanded
+	return expanded, nil
+}
+
+func toInt64(val driver.Value) (int64, bool) {
+	typedVal := reflect.ValueOf(val)
+	switch typedVal.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return typedVal.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if typedVal.Uint() > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(typedVal.Uint()), true
+	}
+	return 0, false
+}
+
+// isList tells IN_ list, []byte and json.RawMessage are scalar value
+func isList(list reflect.Value) bool {
+	if list.Kind() == reflect.Slice {
+		return list.Type().Elem().Kind() != reflect.Uint8
+	}
+	return list.Kind() == reflect.Array
+}
+
+func (stmt *Stmt) bindError(cause error, argName string, inputIndex int) error {
+	return &BindError{cause, argName, inputIndex, stmt.translatedSql.sql}
+}
+
+func (stmt *Stmt) format(args []driver.Value) (string, error) {
 	formattedSql := stmt.translatedSql.sql
 	if stmt.translatedSql.strParamCount > 0 {
 		formatArgs := make([]interface{}, stmt.translatedSql.strParamCount)
 		for i, v := range args[:stmt.translatedSql.strParamCount] {
-			formatArgs[i] = v
+			name := stmt.translatedSql.strParamName(i)
+			if strings.HasPrefix(name, "ORDER_BY_") {
+				formatted, err := formatOrderBy(v, stmt.translatedSql.orderByColumns[name])
+				if err != nil {
+					return "", stmt.bindError(err, name, -1)
+				}
+				formatArgs[i] = formatted
+			} else if stmt.translatedSql.isStrParam(i) {
+				formatted, err := formatStrArg(v)
+				if err != nil {
+					return "", stmt.bindError(err, stmt.translatedSql.strParamName(i), -1)
+				}
+				formatArgs[i] = formatted
+			} else {
+				formatArgs[i] = formatHintArg(v)
+			}
 		}
 		formattedSql = fmt.Sprintf(stmt.translatedSql.sql, formatArgs...)
 	}
@@ -223,14 +572,41 @@ func (stmt *Stmt) format(args []driver.Value) string {
 		fmt.Fprintln(os.Stderr, fmt.Sprintf(">>> %v\n%s\n%v\n", time.Now(), formattedSql,
 			args[stmt.translatedSql.strParamCount:]))
 	}
-	return formattedSql
+	return formattedSql, nil
 }
 
-func (stmt *Stmt) prepare(formattedSql string) (driver.Stmt, error) {
+// formatHintArg escapes the value as json string content, / is escaped so that */ can not end the comment
+func formatHintArg(arg driver.Value) string {
+	encoded, _ := json.Marshal(toStringValue(arg))
+	return strings.Replace(string(encoded[1:len(encoded)-1]), "/", `\/`, -1)
+}
+
+// formatStrArg only let Literal, valid Identifier and number into sql text
+func formatStrArg(arg driver.Value) (interface{}, error) {
+	switch typedArg := arg.(type) {
+	case Literal:
+		return string(typedArg), nil
+	case Identifier:
+		if !isValidIdentifier(string(typedArg)) {
+			return nil, ErrInvalidIdentifier
+		}
+		return string(typedArg), nil
+	case string:
+		if !isValidIdentifier(typedArg) {
+			return nil, ErrInvalidIdentifier
+		}
+		return typedArg, nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return typedArg, nil
+	}
+	return nil, ErrInvalidIdentifier
+}
+
+func (stmt *Stmt) prepare(ctx context.Context, formattedSql string) (driver.Stmt, error) {
 	obj := stmt.objs[formattedSql]
 	if obj == nil {
 		var err error
-		obj, err = stmt.conn.obj.Prepare(formattedSql)
+		obj, err = stmt.conn.prepareContext(ctx, formattedSql)
 		if err != nil {
 			stmt.conn.Error = err
 			return nil, err
@@ -239,3 +615,56 @@ func (stmt *Stmt) prepare(formattedSql string) (driver.Stmt, error) {
 	}
 	return obj, nil
 }
+
+// formatOrderBy accepts Order, []Order or "column DESC", nil means no ORDER BY at all
+func formatOrderBy(arg driver.Value, allowed []string) (string, error) {
+	var orders []Order
+	switch typedArg := arg.(type) {
+	case nil:
+		return "", nil
+	case Order:
+		orders = []Order{typedArg}
+	case []Order:
+		orders = typedArg
+	case string:
+		fields := strings.Fields(typedArg)
+		if len(fields) == 0 || len(fields) > 2 {
+			return "", ErrInvalidOrderBy
+		}
+		order := Order{Column: fields[0]}
+		if len(fields) == 2 {
+			switch strings.ToUpper(fields[1]) {
+			case "ASC":
+			case "DESC":
+				order.Desc = true
+			default:
+				return "", ErrInvalidOrderBy
+			}
+		}
+		orders = []Order{order}
+	default:
+		return "", ErrInvalidOrderBy
+	}
+	if len(orders) == 0 {
+		return "", nil
+	}
+	parts := make([]string, len(orders))
+	for i, order := range orders {
+		isAllowed := false
+		for _, column := range allowed {
+			if column == order.Column {
+				isAllowed = true
+				break
+			}
+		}
+		if !isAllowed {
+			return "", ErrInvalidOrderBy
+		}
+		if order.Desc {
+			parts[i] = order.Column + " DESC"
+		} else {
+			parts[i] = order.Column
+		}
+	}
+	return "ORDER BY " + strings.Join(parts, ", "), nil
+}
@@ -1,10 +1,13 @@
 package dingo
 
 import (
+	"database/sql/driver"
 	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/require"
 	"testing"
 	"io"
+	"math"
+	"time"
 )
 
 func Test_select(t *testing.T) {
@@ -45,7 +48,7 @@ func Test_select_in(t *testing.T) {
 		"SELECT * FROM account WHERE entity_id IN :STR_ENTITY_IDS")
 	defer stmt.Close()
 	rows, err := stmt.Query(
-		"STR_ENTITY_IDS", Tuple("account1"))
+		"STR_ENTITY_IDS", TupleLiteral("account1"))
 	should.Nil(err)
 	defer rows.Close()
 	should.Nil(rows.Next())
@@ -129,3 +132,325 @@ func Test_batch_insert(t *testing.T) {
 	should.Nil(err)
 	should.Equal(int64(2), rowsAffected)
 }
+
+func Test_bind_error(t *testing.T) {
+	should := require.New(t)
+	translated, err := TranslateE("SELECT * FROM account WHERE entity_id=:entity_id")
+	should.Nil(err)
+	stmt := openFakeConn(&fakeConn{}).Statement(translated)
+	_, err = stmt.Exec("entity_id")
+	should.Equal(&BindError{ErrInvalidInputs, "", 0, translated.sql}, err)
+	_, err = stmt.Exec("entity_id", "account1", "event_id", int64(1))
+	should.Equal(&BindError{ErrArgumentNotFound, "event_id", 2, translated.sql}, err)
+	_, err = stmt.Query(1, "account1")
+	should.Equal(&BindError{ErrInvalidInputs, "1", 0, translated.sql}, err)
+	_, err = stmt.Exec("PREPARED", "true")
+	should.Equal(&BindError{ErrInvalidInputs, "PREPARED", 1, translated.sql}, err)
+}
+
+func Test_exec_IN_list(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{}
+	conn := openFakeConn(fake)
+	stmt := conn.TranslateStatement("DELETE FROM account WHERE entity_id IN :IN_ids OR event_id IN :IN_ids")
+	defer stmt.Close()
+	_, err := stmt.Exec("IN_ids", []string{"account1", "account2"})
+	should.Nil(err)
+	should.Equal("DELETE FROM account WHERE entity_id IN (?, ?) OR event_id IN (?, ?)", fake.prepared[0])
+	should.Equal([]driver.Value{"account1", "account2", "account1", "account2"}, fake.args)
+	_, err = stmt.Exec("IN_ids", []string{"account3", "account4"})
+	should.Nil(err)
+	should.Equal(1, len(fake.prepared))
+	_, err = stmt.Exec("IN_ids", []int64{1, 2, 3})
+	should.Nil(err)
+	should.Equal("DELETE FROM account WHERE entity_id IN (?, ?, ?) OR event_id IN (?, ?, ?)", fake.prepared[1])
+	_, err = stmt.Exec("IN_ids", []int64{})
+	should.Nil(err)
+	should.Equal("DELETE FROM account WHERE entity_id IN (NULL) OR event_id IN (NULL)", fake.prepared[2])
+	should.Equal([]driver.Value{}, fake.args)
+	_, err = stmt.Exec("IN_ids", "account1")
+	should.Equal(ErrInvalidInputs, err.(*BindError).Cause)
+	_, err = stmt.Exec("IN_ids", []byte("account1"))
+	should.Equal(ErrInvalidInputs, err.(*BindError).Cause)
+	_, err = stmt.Exec("IN_ids", [][]byte{[]byte("account1"), []byte("account2")})
+	should.Nil(err)
+	should.Equal([]driver.Value{[]byte("account1"), []byte("account2"), []byte("account1"), []byte("account2")}, fake.args)
+	// batch insert row is bound without expand
+	_, _, err = stmt.(*Stmt).toArgs([]driver.Value{"IN_ids", []int64{}})
+	should.Equal(ErrEmptyList, err.(*BindError).Cause)
+}
+
+func Test_tuple(t *testing.T) {
+	should := require.New(t)
+	should.Equal(`('it''s',X'615c27',X'0102',NULL,TRUE,1,'2018-01-02 03:04:05')`, Tuple(
+		"it's", `a\'`, []byte{1, 2}, nil, true, 1, time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)))
+	should.Equal(Literal(`('it''s',E'a\\''','\x0102'::bytea,FALSE)`), Postgres.Tuple(
+		"it's", `a\'`, []byte{1, 2}, false))
+	should.Equal(Literal("(NULL)"), TupleLiteral())
+}
+
+func Test_exec_STR(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{}
+	conn := openFakeConn(fake)
+	stmt := conn.TranslateStatement("DELETE FROM account_:STR_district WHERE entity_id IN :STR_ids")
+	defer stmt.Close()
+	_, err := stmt.Exec("STR_district", "010", "STR_ids", TupleLiteral("account1", "account2"))
+	should.Nil(err)
+	should.Equal("DELETE FROM account_010 WHERE entity_id IN ('account1','account2')", fake.prepared[0])
+	_, err = stmt.Exec("STR_district", Identifier("v2pro.account"), "STR_ids", TupleLiteral())
+	should.Nil(err)
+	should.Equal("DELETE FROM account_v2pro.account WHERE entity_id IN (NULL)", fake.prepared[1])
+	_, err = stmt.Exec("STR_district", "010 OR 1=1", "STR_ids", TupleLiteral())
+	should.Equal(&BindError{ErrInvalidIdentifier, "STR_district", -1, stmt.(*Stmt).translatedSql.sql}, err)
+	_, err = stmt.Exec("STR_district", 10, "STR_ids", "('account1')")
+	should.Equal(ErrInvalidIdentifier, err.(*BindError).Cause)
+}
+
+func Test_exec_HINT(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{rows: &fakeRows{}}
+	conn := openFakeConn(fake)
+	stmt := conn.TranslateStatement("SELECT * FROM account :HINT_COLUMNS", "trace_id")
+	defer stmt.Close()
+	rows, err := stmt.Query("trace_id", "trace1")
+	should.Nil(err)
+	should.Nil(rows.Close())
+	should.Equal(`SELECT * FROM account /*{"trace_id":"trace1"}*/`, fake.prepared[0])
+	rows, err = stmt.Query("trace_id", `x"}*/ DROP TABLE t; /*`)
+	should.Nil(err)
+	should.Nil(rows.Close())
+	should.Equal(`SELECT * FROM account /*{"trace_id":"x\"}*\/ DROP TABLE t; \/*"}*/`, fake.prepared[1])
+}
+
+func Test_exec_NULLABLE_WHERE(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{}
+	conn := openFakeConn(fake)
+	stmt := conn.TranslateStatement("DELETE FROM account WHERE :NULLABLE_WHERE_COLUMNS",
+		"entity_id", "event_id")
+	defer stmt.Close()
+	_, err := stmt.Exec("entity_id", "account1", "event_id", nil)
+	should.Nil(err)
+	should.Equal("DELETE FROM account WHERE entity_id=? AND event_id IS NULL", fake.prepared[0])
+	should.Equal([]driver.Value{"account1"}, fake.args)
+	_, err = stmt.Exec("entity_id", "account1", "event_id", int64(1))
+	should.Nil(err)
+	should.Equal("DELETE FROM account WHERE entity_id=? AND event_id=?", fake.prepared[1])
+	should.Equal([]driver.Value{"account1", int64(1)}, fake.args)
+}
+
+func Test_exec_IF(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{}
+	conn := openFakeConn(fake)
+	stmt := conn.TranslateStatement("DELETE FROM account WHERE entity_id=:entity_id /*IF event_id*/AND event_id=:event_id/*END*/")
+	defer stmt.Close()
+	_, err := stmt.Exec("entity_id", "account1")
+	should.Nil(err)
+	should.Equal("DELETE FROM account WHERE entity_id=? ", fake.prepared[0])
+	should.Equal([]driver.Value{"account1"}, fake.args)
+	_, err = stmt.Exec("entity_id", "account1", "event_id", int64(1))
+	should.Nil(err)
+	should.Equal("DELETE FROM account WHERE entity_id=? AND event_id=?", fake.prepared[1])
+	should.Equal([]driver.Value{"account1", int64(1)}, fake.args)
+	_, err = stmt.Exec("entity_id", "account2")
+	should.Nil(err)
+	should.Equal(2, len(fake.prepared))
+	stmt = conn.TranslateStatement("DELETE FROM account WHERE entity_id=:entity_id " +
+		"/*IF event_id*/AND event_id=:event_id AND event_name IN :IN_names/*END*/")
+	defer stmt.Close()
+	_, err = stmt.Exec("entity_id", "account1", "IN_names", []string{"created", "updated"})
+	should.Nil(err)
+	should.Equal("DELETE FROM account WHERE entity_id=? ", fake.prepared[2])
+	should.Equal([]driver.Value{"account1"}, fake.args)
+	_, err = stmt.Exec("entity_id", "account1", "event_id", int64(1), "IN_names", []string{"created", "updated"})
+	should.Nil(err)
+	should.Equal("DELETE FROM account WHERE entity_id=? AND event_id=? AND event_name IN (?, ?)", fake.prepared[3])
+	should.Equal([]driver.Value{"account1", int64(1), "created", "updated"}, fake.args)
+}
+
+func Test_query_ORDER_BY_and_LIMIT(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{rows: &fakeRows{columns: []string{"entity_id"}}}
+	conn := openFakeConn(fake)
+	stmt := conn.TranslateStatement("SELECT * FROM account :ORDER_BY_COLUMNS :LIMIT :OFFSET",
+		Postgres, "entity_id", "event_id")
+	defer stmt.Close()
+	rows, err := stmt.Query("ORDER_BY_COLUMNS", Desc("event_id"), "LIMIT", 10, "OFFSET", uint(20))
+	should.Nil(err)
+	should.Nil(rows.Close())
+	should.Equal("SELECT * FROM account ORDER BY event_id DESC LIMIT $1 OFFSET $2", fake.prepared[0])
+	should.Equal([]driver.Value{int64(10), int64(20)}, fake.args)
+	rows, err = stmt.Query("ORDER_BY_COLUMNS", "entity_id asc")
+	should.Nil(err)
+	should.Nil(rows.Close())
+	should.Equal("SELECT * FROM account ORDER BY entity_id  ", fake.prepared[1])
+	_, err = stmt.Query("ORDER_BY_COLUMNS", "state")
+	should.Equal(ErrInvalidOrderBy, err.(*BindError).Cause)
+	_, err = stmt.Query("ORDER_BY_COLUMNS", "entity_id; DROP TABLE account")
+	should.Equal(ErrInvalidOrderBy, err.(*BindError).Cause)
+	_, err = stmt.Query("LIMIT", "10")
+	should.Equal(ErrInvalidInputs, err.(*BindError).Cause)
+	_, err = stmt.Query("LIMIT", uint64(math.MaxUint64))
+	should.Equal(ErrInvalidInputs, err.(*BindError).Cause)
+	mysqlStmt := conn.TranslateStatement("SELECT * FROM account :ORDER_BY_COLUMNS :LIMIT :OFFSET",
+		"entity_id", "event_id")
+	defer mysqlStmt.Close()
+	rows, err = mysqlStmt.Query("ORDER_BY_COLUMNS", "event_id", "LIMIT", 10, "OFFSET", 20)
+	should.Nil(err)
+	should.Nil(rows.Close())
+	should.Equal("SELECT * FROM account ORDER BY event_id LIMIT ? OFFSET ?", fake.prepared[2])
+	should.Equal([]driver.Value{int64(10), int64(20)}, fake.args)
+	rows, err = mysqlStmt.Query("ORDER_BY_COLUMNS", "event_id", "OFFSET", 20)
+	should.Nil(err)
+	should.Nil(rows.Close())
+	should.Equal("SELECT * FROM account ORDER BY event_id  LIMIT 18446744073709551615 OFFSET ?", fake.prepared[3])
+	should.Equal([]driver.Value{int64(20)}, fake.args)
+	likeStmt := conn.TranslateStatement("SELECT * FROM account WHERE entity_id LIKE 'abc%' :ORDER_BY_COLUMNS",
+		"entity_id")
+	defer likeStmt.Close()
+	should.Equal("SELECT * FROM account WHERE entity_id LIKE 'abc%%' %v", likeStmt.(*Stmt).translatedSql.sql)
+	rows, err = likeStmt.Query("ORDER_BY_COLUMNS", "entity_id")
+	should.Nil(err)
+	should.Nil(rows.Close())
+	should.Equal("SELECT * FROM account WHERE entity_id LIKE 'abc%' ORDER BY entity_id", fake.prepared[4])
+	should.Equal("SELECT * FROM account WHERE entity_id LIKE 'abc%'",
+		Translate("SELECT * FROM account WHERE entity_id LIKE 'abc%'").(*TranslatedSql).sql)
+}
+
+func Test_exec_strict(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{rows: &fakeRows{
+		columns: []string{"entity_id", "state", "version"},
+		data:    [][]driver.Value{{[]byte("account1"), []byte("{}"), int64(1)}},
+	}}
+	conn := openFakeConn(fake)
+	stmt := conn.TranslateStatement("UPDATE account SET :UPDATE_COLUMNS WHERE entity_id=:entity_id",
+		"event_id", "state")
+	defer stmt.Close()
+	_, err := stmt.Exec("entity_id", "account1")
+	should.Nil(err)
+	stmt.(*Stmt).SetStrict(true)
+	_, err = stmt.Exec("entity_id", "account1")
+	should.Equal(&UnboundError{[]string{"event_id", "state"}, []string{}, stmt.(*Stmt).translatedSql.sql}, err)
+	query := conn.TranslateStatement("SELECT * FROM account")
+	defer query.Close()
+	rows, err := query.Query()
+	should.Nil(err)
+	should.Nil(rows.Next())
+	_, err = stmt.Exec("ROW", rows, "event_id", int64(2))
+	should.Equal(&UnboundError{[]string{}, []string{"version"}, stmt.(*Stmt).translatedSql.sql}, err)
+	should.Nil(rows.Close())
+	conn.SetStrict(true)
+	_, err = conn.TranslateStatement("DELETE FROM account WHERE entity_id=:entity_id").Exec()
+	should.Equal([]string{"entity_id"}, err.(*UnboundError).ArgNames)
+}
+
+func Test_exec_JSON(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{}
+	conn := openFakeConn(fake)
+	stmt := conn.TranslateStatement("INSERT account :INSERT_COLUMNS",
+		JSONColumns("COLUMNS", []string{"state"}, "entity_id", "state", "command"))
+	defer stmt.Close()
+	_, err := stmt.Exec("entity_id", "account1", "state", map[string]int{"balance": 1},
+		"command", JSON{[]string{"charge"}})
+	should.Nil(err)
+	should.Equal([]driver.Value{"account1", []byte(`{"balance":1}`), []byte(`["charge"]`)}, fake.args)
+	_, err = stmt.Exec("entity_id", "account1", "state", `{"balance":2}`, "command", nil)
+	should.Nil(err)
+	should.Equal([]driver.Value{"account1", `{"balance":2}`, nil}, fake.args)
+	_, err = stmt.Exec("entity_id", "account1", "state", make(chan int))
+	should.Equal(ErrInvalidInputs, err.(*BindError).Cause)
+}
+
+type fakeDriver struct {
+	conn *fakeConn
+}
+
+func (drv *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	return drv.conn, nil
+}
+
+func openFakeConn(fake *fakeConn) *Conn {
+	conn, _ := Open(&fakeDriver{fake}, "fake")
+	return conn.(*Conn)
+}
+
+type fakeConn struct {
+	prepared []string
+	args     []driver.Value
+	rows     *fakeRows
+}
+
+func (conn *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	conn.prepared = append(conn.prepared, query)
+	return &fakeStmt{conn}, nil
+}
+
+func (conn *fakeConn) Close() error {
+	return nil
+}
+
+func (conn *fakeConn) Begin() (driver.Tx, error) {
+	return nil, driver.ErrSkip
+}
+
+type fakeStmt struct {
+	conn *fakeConn
+}
+
+func (stmt *fakeStmt) Close() error {
+	return nil
+}
+
+func (stmt *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (stmt *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	stmt.conn.args = args
+	return driver.RowsAffected(1), nil
+}
+
+func (stmt *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	stmt.conn.args = args
+	return stmt.conn.rows, nil
+}
+
+type fakeRows struct {
+	columns []string
+	data    [][]driver.Value
+	// returned when data is exhausted, io.EOF if nil
+	err error
+	// database type name of the columns, empty if not set
+	types []string
+}
+
+func (rows *fakeRows) Columns() []string {
+	return rows.columns
+}
+
+func (rows *fakeRows) ColumnTypeDatabaseTypeName(index int) string {
+	if index < len(rows.types) {
+		return rows.types[index]
+	}
+	return ""
+}
+
+func (rows *fakeRows) Close() error {
+	return nil
+}
+
+func (rows *fakeRows) Next(dest []driver.Value) error {
+	if len(rows.data) == 0 {
+		if rows.err != nil {
+			return rows.err
+		}
+		return io.EOF
+	}
+	copy(dest, rows.data[0])
+	rows.data = rows.data[1:]
+	return nil
+}
@@ -0,0 +1,78 @@
+package dingo
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrUnexpectedColumnArgument = errors.New("UnexpectedColumnArgument")
+var ErrUnknownColumnGroup = errors.New("UnknownColumnGroup")
+var ErrEmptyColumnGroup = errors.New("EmptyColumnGroup")
+var ErrUpperCaseParameter = errors.New("UpperCaseParameter")
+var ErrMissingParameterName = errors.New("MissingParameterName")
+var ErrMissingConflictKeys = errors.New("MissingConflictKeys")
+var ErrUnbalancedCondition = errors.New("UnbalancedCondition")
+var ErrArgumentNotFound = errors.New("ArgumentNotFound")
+var ErrInvalidInputs = errors.New("InvalidInputs")
+var ErrEmptyList = errors.New("EmptyList")
+var ErrInvalidIdentifier = errors.New("InvalidIdentifier")
+var ErrInvalidOrderBy = errors.New("InvalidOrderBy")
+var ErrUnboundArguments = errors.New("UnboundArguments")
+var ErrNoRows = errors.New("NoRows")
+
+/*
+TranslateError tells which named parameter of the sql can not be translated.
+Offset is the byte offset of the colon in sql, -1 if the error is not from sql text
+*/
+type TranslateError struct {
+	Cause   error
+	VarName string
+	Offset  int
+	Sql     string
+}
+
+func (err *TranslateError) Error() string {
+	if err.Offset < 0 {
+		return fmt.Sprintf("%s: %v\nsql: %v", err.Cause.Error(), err.VarName, err.Sql)
+	}
+	return fmt.Sprintf("%s: %v at offset %v\nsql: %v", err.Cause.Error(), err.VarName, err.Offset, err.Sql)
+}
+
+func (err *TranslateError) Unwrap() error {
+	return err.Cause
+}
+
+/*
+BindError tells which input of Stmt.Exec or Stmt.Query can not be bound to the translated sql.
+InputIndex is the index in the inputs, -1 if the error is found after binding
+*/
+type BindError struct {
+	Cause      error
+	ArgName    string
+	InputIndex int
+	Sql        string
+}
+
+func (err *BindError) Error() string {
+	return fmt.Sprintf("%s: %v at input %v\nsql: %v", err.Cause.Error(), err.ArgName, err.InputIndex, err.Sql)
+}
+
+func (err *BindError) Unwrap() error {
+	return err.Cause
+}
+
+// UnboundError is returned by Stmt in strict mode
+type UnboundError struct {
+	ArgNames      []string
+	UnusedColumns []string
+	Sql           string
+}
+
+func (err *UnboundError) Error() string {
+	return fmt.Sprintf("%s: %v, unused row columns: %v\nsql: %v",
+		ErrUnboundArguments.Error(), err.ArgNames, err.UnusedColumns, err.Sql)
+}
+
+func (err *UnboundError) Unwrap() error {
+	return ErrUnboundArguments
+}
@@ -0,0 +1,177 @@
+package dingo
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+stream the remaining rows to writer, one row in memory at a time.
+time is written in the location of the conn, DATE and DATETIME sent as text are parsed
+if the driver reports the column type
+*/
+
+type CSVOptions struct {
+	// ',' if not set
+	Comma rune
+	// NULL is written as empty field if not set
+	Null string
+	// "2006-01-02 15:04:05" if not set
+	TimeFormat string
+	// Columns() is written as the first record unless NoHeader
+	NoHeader bool
+}
+
+// WriteCSV writes the remaining rows as csv records, time is formatted by opts.TimeFormat
+func (rows *Rows) WriteCSV(w io.Writer, opts CSVOptions) error {
+	writer := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		writer.Comma = opts.Comma
+	}
+	if opts.TimeFormat == "" {
+		opts.TimeFormat = "2006-01-02 15:04:05"
+	}
+	if !opts.NoHeader {
+		if err := writer.Write(rows.Columns()); err != nil {
+			return err
+		}
+	}
+	isTime := rows.timeColumns()
+	record := make([]string, len(rows.row))
+	for {
+		err := rows.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for i, val := range rows.row {
+			if isTime[i] {
+				val = rows.parseTime(val)
+			}
+			switch typedVal := val.(type) {
+			case nil:
+				record[i] = opts.Null
+			case time.Time:
+				record[i] = typedVal.In(rows.conn.Location()).Format(opts.TimeFormat)
+			case float64:
+				record[i] = strconv.FormatFloat(typedVal, 'f', -1, 64)
+			default:
+				record[i] = toStringValue(typedVal)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteNDJSON writes each row as json object keyed by Columns(), NULL, NaN and Inf are null, time is RFC3339
+func (rows *Rows) WriteNDJSON(w io.Writer) error {
+	isTime := rows.timeColumns()
+	keys := make([][]byte, len(rows.row))
+	for i, column := range rows.Columns() {
+		key, err := json.Marshal(column)
+		if err != nil {
+			return err
+		}
+		keys[i] = key
+	}
+	line := bytes.NewBuffer(make([]byte, 0, 256))
+	for {
+		err := rows.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line.Reset()
+		line.WriteByte('{')
+		for i, val := range rows.row {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			line.Write(keys[i])
+			line.WriteByte(':')
+			if isTime[i] {
+				val = rows.parseTime(val)
+			}
+			if err := rows.writeJSONValue(line, val); err != nil {
+				return err
+			}
+		}
+		line.WriteString("}\n")
+		if _, err := w.Write(line.Bytes()); err != nil {
+			return err
+		}
+	}
+}
+
+func (rows *Rows) writeJSONValue(line *bytes.Buffer, val driver.Value) error {
+	switch typedVal := val.(type) {
+	case nil:
+		line.WriteString("null")
+		return nil
+	case int64:
+		line.WriteString(strconv.FormatInt(typedVal, 10))
+		return nil
+	case bool:
+		line.WriteString(strconv.FormatBool(typedVal))
+		return nil
+	case float64:
+		// json has no NaN or Inf
+		if math.IsNaN(typedVal) || math.IsInf(typedVal, 0) {
+			line.WriteString("null")
+			return nil
+		}
+	case time.Time:
+		val = typedVal.In(rows.conn.Location()).Format(time.RFC3339Nano)
+	case []byte:
+		val = string(typedVal)
+	}
+	encoded, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+	line.Write(encoded)
+	return nil
+}
+
+// timeColumns tells DATE, DATETIME and TIMESTAMP column by the type name of the driver
+func (rows *Rows) timeColumns() []bool {
+	isTime := make([]bool, len(rows.row))
+	typed, ok := rows.obj.(driver.RowsColumnTypeDatabaseTypeName)
+	if !ok {
+		return isTime
+	}
+	for i := range isTime {
+		switch strings.ToUpper(typed.ColumnTypeDatabaseTypeName(i)) {
+		case "DATE", "DATETIME", "TIMESTAMP", "TIMESTAMPTZ":
+			isTime[i] = true
+		}
+	}
+	return isTime
+}
+
+// parseTime reads time text in the location of the conn, text not parsed such as 0000-00-00 is kept
+func (rows *Rows) parseTime(val driver.Value) driver.Value {
+	if _, isText := textOf(val); !isText {
+		return val
+	}
+	asTime, err := toTime(val, rows.conn.Location())
+	if err != nil {
+		return val
+	}
+	return asTime
+}
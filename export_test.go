@@ -0,0 +1,76 @@
+package dingo
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+	"time"
+)
+
+func Test_write_CSV(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"entity_id", "version", "balance", "updated_at", "state"},
+		[]driver.Value{[]byte("account,1"), int64(1), float64(1.5), time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC), nil},
+		[]driver.Value{"account2", int64(2), float64(0), nil, []byte(`{"a":"b"}`)})
+	defer rows.Close()
+	rows.conn.SetLocation(time.FixedZone("UTC+8", 8*3600))
+	buf := &bytes.Buffer{}
+	should.Nil(rows.WriteCSV(buf, CSVOptions{Null: `\N`}))
+	should.Equal("entity_id,version,balance,updated_at,state\n"+
+		"\"account,1\",1,1.5,2018-01-02 11:04:05,\\N\n"+
+		"account2,2,0,\\N,\"{\"\"a\"\":\"\"b\"\"}\"\n", buf.String())
+}
+
+func Test_write_CSV_options(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"entity_id", "updated_at"},
+		[]driver.Value{[]byte("account1"), time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)})
+	defer rows.Close()
+	rows.conn.SetLocation(time.UTC)
+	buf := &bytes.Buffer{}
+	should.Nil(rows.WriteCSV(buf, CSVOptions{Comma: '\t', TimeFormat: time.RFC3339, NoHeader: true}))
+	should.Equal("account1\t2018-01-02T03:04:05Z\n", buf.String())
+}
+
+func Test_write_NDJSON(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"entity_id", "version", "balance", "enabled", "updated_at", "state"},
+		[]driver.Value{[]byte("account\"1"), int64(1), float64(1.5), true, time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC), nil})
+	defer rows.Close()
+	rows.conn.SetLocation(time.UTC)
+	buf := &bytes.Buffer{}
+	should.Nil(rows.WriteNDJSON(buf))
+	should.Equal(`{"entity_id":"account\"1","version":1,"balance":1.5,"enabled":true,`+
+		`"updated_at":"2018-01-02T03:04:05Z","state":null}`+"\n", buf.String())
+}
+
+func Test_write_DATETIME_text(t *testing.T) {
+	should := require.New(t)
+	conn := openFakeConn(&fakeConn{rows: &fakeRows{
+		columns: []string{"entity_id", "created_at", "updated_at"},
+		types:   []string{"VARCHAR", "DATETIME", "TIMESTAMP"},
+		data: [][]driver.Value{
+			{[]byte("2018-01-02 03:04:05"), []byte("2018-01-02 03:04:05"), []byte("0000-00-00 00:00:00")},
+		},
+	}})
+	conn.SetLocation(time.UTC)
+	query, err := conn.TranslateStatement("SELECT * FROM account").Query()
+	should.Nil(err)
+	rows := query.(*Rows)
+	defer rows.Close()
+	buf := &bytes.Buffer{}
+	should.Nil(rows.WriteCSV(buf, CSVOptions{TimeFormat: time.RFC3339, NoHeader: true}))
+	should.Equal("2018-01-02 03:04:05,2018-01-02T03:04:05Z,0000-00-00 00:00:00\n", buf.String())
+}
+
+func Test_write_NDJSON_NaN(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"nan", "inf", "balance"},
+		[]driver.Value{math.NaN(), math.Inf(-1), float64(1.5)})
+	defer rows.Close()
+	buf := &bytes.Buffer{}
+	should.Nil(rows.WriteNDJSON(buf))
+	should.Equal(`{"nan":null,"inf":null,"balance":1.5}`+"\n", buf.String())
+}
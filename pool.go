@@ -3,7 +3,9 @@ package dingo
 import (
 	"database/sql/driver"
 	"errors"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 /*
@@ -16,17 +18,33 @@ type Pool struct {
 	dsn            string
 	maxActiveCount int32
 	activeCount    int32
+	lock           sync.Mutex
+	location       *time.Location
 }
 
 var TooManyConcurrentConnections = errors.New("TooManyConcurrentConnections")
 
 func NewPool(drv driver.Driver, dsn string, size int32) *Pool {
-	return &Pool{make(chan *Conn, size), drv, dsn, size, 0}
+	return &Pool{make(chan *Conn, size), drv, dsn, size, 0, sync.Mutex{}, nil}
+}
+
+// SetLocation is applied to connection borrowed after it, unless the connection sets its own
+func (pool *Pool) SetLocation(location *time.Location) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	pool.location = location
+}
+
+func (pool *Pool) getLocation() *time.Location {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	return pool.location
 }
 
 func (pool *Pool) Borrow() (*Conn, error) {
 	select {
 	case conn := <-pool.conns:
+		conn.poolLocation = pool.getLocation()
 		return conn, nil
 	default:
 		if atomic.AddInt32(&pool.activeCount, 1) > pool.maxActiveCount {
@@ -36,6 +54,7 @@ func (pool *Pool) Borrow() (*Conn, error) {
 		if err != nil {
 			return nil, err
 		}
+		conn.(*Conn).poolLocation = pool.getLocation()
 		conn.(*Conn).onClose = func(conn *Conn) error {
 			atomic.AddInt32(&pool.activeCount, -1)
 			conn.onClose = pool.release
@@ -47,12 +66,14 @@ func (pool *Pool) Borrow() (*Conn, error) {
 
 func (pool *Pool) release(conn *Conn) error {
 	if conn.Error != nil {
-		return conn.obj.Close()
+		return conn.closeObj()
 	}
+	// location set by the borrower does not leak to the next one
+	conn.location = nil
 	select {
 	case pool.conns <- conn:
 		return nil
 	default:
-		return conn.obj.Close()
+		return conn.closeObj()
 	}
 }
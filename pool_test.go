@@ -4,6 +4,7 @@ import (
 	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func Test_borrow_and_close(t *testing.T) {
@@ -28,3 +29,38 @@ func Test_borrow_close_borrow_close(t *testing.T) {
 	should.Nil(conn.Close())
 	should.Equal(int32(0), pool.activeCount)
 }
+
+func Test_pool_location(t *testing.T) {
+	should := require.New(t)
+	pool := NewPool(&fakeDriver{&fakeConn{}}, "fake", 2)
+	pool.SetLocation(time.UTC)
+	conn, err := pool.Borrow()
+	should.Nil(err)
+	should.Equal(time.UTC, conn.Location())
+	should.Nil(conn.Close())
+	pool.SetLocation(nil)
+	conn, err = pool.Borrow()
+	should.Nil(err)
+	should.Equal(DefaultLocation, conn.Location())
+	location := time.FixedZone("UTC-5", -5*3600)
+	conn.SetLocation(location)
+	should.Nil(conn.Close())
+	pool.SetLocation(time.UTC)
+	conn, err = pool.Borrow()
+	should.Nil(err)
+	should.Equal(time.UTC, conn.Location())
+	conn.SetLocation(location)
+	should.Equal(location, conn.Location())
+	conn.SetLocation(nil)
+	should.Equal(time.UTC, conn.Location())
+	should.Nil(conn.Close())
+	done := make(chan bool)
+	go func() {
+		pool.SetLocation(location)
+		done <- true
+	}()
+	conn, err = pool.Borrow()
+	should.Nil(err)
+	should.Nil(conn.Close())
+	<-done
+}
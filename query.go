@@ -0,0 +1,124 @@
+//go:build go1.18
+
+package dingo
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+/*
+typed helpers translate, bind, read and close in one call, the template is translated through TranslateCached
+with the column args, nil columns if the template has no column group,
+QueryAll[T](conn, "SELECT :SELECT_COLUMNS FROM account WHERE entity_id=:entity_id",
+	[]interface{}{"entity_id", "state"}, "entity_id", "account1").
+T is struct scanned by tag db:"entity_id", pointer to struct, or scalar read from the first column
+*/
+
+// QueryAll reads every row as T, rows and statement are closed before return
+func QueryAll[T any](conn *Conn, template string, columns []interface{}, inputs ...driver.Value) (result []T, err error) {
+	rows, closeQuery, err := conn.queryTemplate(template, columns, inputs)
+	if err != nil {
+		return nil, err
+	}
+	defer closeQuery(&err)
+	result = []T{}
+	for {
+		err = rows.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var elem T
+		err = rows.scanValue(reflect.ValueOf(&elem).Elem())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, elem)
+	}
+}
+
+// QueryOne reads the first row as T, ErrNoRows if the result is empty
+func QueryOne[T any](conn *Conn, template string, columns []interface{}, inputs ...driver.Value) (result T, err error) {
+	rows, closeQuery, err := conn.queryTemplate(template, columns, inputs)
+	if err != nil {
+		return result, err
+	}
+	defer closeQuery(&err)
+	err = rows.Next()
+	if err == io.EOF {
+		return result, ErrNoRows
+	}
+	if err != nil {
+		return result, err
+	}
+	err = rows.scanValue(reflect.ValueOf(&result).Elem())
+	return result, err
+}
+
+// ExecNamed executes the template, the statement is closed before return
+func ExecNamed(conn *Conn, template string, columns []interface{}, inputs ...driver.Value) (driver.Result, error) {
+	translatedSql, err := TranslateCached(template, columns...)
+	if err != nil {
+		return nil, err
+	}
+	stmt := conn.Statement(translatedSql)
+	defer stmt.Close()
+	return stmt.Exec(inputs...)
+}
+
+// queryTemplate returns the rows and the func closing both rows and statement, reporting close error if no other
+func (conn *Conn) queryTemplate(template string, columns []interface{}, inputs []driver.Value) (*Rows, func(*error), error) {
+	translatedSql, err := TranslateCached(template, columns...)
+	if err != nil {
+		return nil, nil, err
+	}
+	stmt := conn.Statement(translatedSql)
+	rows, err := stmt.Query(inputs...)
+	if err != nil {
+		stmt.Close()
+		return nil, nil, err
+	}
+	closeQuery := func(err *error) {
+		rowsErr := rows.Close()
+		stmtErr := stmt.Close()
+		if *err != nil {
+			return
+		}
+		if rowsErr != nil {
+			*err = rowsErr
+		} else if stmtErr != nil {
+			*err = stmtErr
+		}
+	}
+	return rows.(*Rows), closeQuery, nil
+}
+
+// scanValue reads current row into struct, pointer to struct or scalar
+func (rows *Rows) scanValue(val reflect.Value) error {
+	if val.Kind() == reflect.Ptr && val.Type().Elem().Kind() == reflect.Struct &&
+		!isScalarStruct(val.Type().Elem()) {
+		elem := reflect.New(val.Type().Elem())
+		if err := rows.scanStruct(elem.Elem()); err != nil {
+			return err
+		}
+		val.Set(elem)
+		return nil
+	}
+	if val.Kind() == reflect.Struct && !isScalarStruct(val.Type()) {
+		return rows.scanStruct(val)
+	}
+	if len(rows.row) == 0 {
+		return fmt.Errorf("%v can not be read from empty row", val.Type())
+	}
+	return setField(val, rows.row[0], rows.conn.Location())
+}
+
+// isScalarStruct tells struct read from one column, such as time.Time and sql.NullString
+func isScalarStruct(typ reflect.Type) bool {
+	return typ == timeType || reflect.PtrTo(typ).Implements(scannerType)
+}
@@ -0,0 +1,73 @@
+//go:build go1.18
+
+package dingo
+
+import (
+	"database/sql/driver"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func Test_query_all(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{rows: &fakeRows{
+		columns: []string{"entity_id", "event_id"},
+		data: [][]driver.Value{
+			{[]byte("account1"), int64(1)},
+			{[]byte("account2"), int64(2)},
+		},
+	}}
+	conn := openFakeConn(fake)
+	events, err := QueryAll[accountEvent](conn,
+		"SELECT :SELECT_COLUMNS FROM account_event WHERE entity_id=:entity_id",
+		[]interface{}{"entity_id", "event_id"}, "entity_id", "account1")
+	should.Nil(err)
+	should.Equal(2, len(events))
+	should.Equal("account1", events[0].EntityId)
+	should.Equal(int32(2), events[1].EventId)
+	should.Equal("SELECT entity_id, event_id FROM account_event WHERE entity_id=?", fake.prepared[0])
+	should.Equal([]driver.Value{"account1"}, fake.args)
+	fake.rows.data = [][]driver.Value{{[]byte("account3"), []byte("x")}}
+	_, err = QueryAll[*accountEvent](conn, "SELECT * FROM account_event", nil)
+	should.NotNil(err)
+	should.Equal("", conn.activeQuerySql)
+	fake.rows.data = [][]driver.Value{{[]byte("account4"), int64(4)}}
+	pointers, err := QueryAll[*accountEvent](conn, "SELECT * FROM account_event", nil)
+	should.Nil(err)
+	should.Equal("account4", pointers[0].EntityId)
+}
+
+func Test_query_one(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{rows: &fakeRows{
+		columns: []string{"count"},
+		data:    [][]driver.Value{{int64(3)}},
+	}}
+	conn := openFakeConn(fake)
+	countSql := "SELECT COUNT(*) FROM account_event"
+	count, err := QueryOne[int](conn, countSql, nil)
+	should.Nil(err)
+	should.Equal(3, count)
+	_, err = QueryOne[int](conn, countSql, nil)
+	should.Equal(ErrNoRows, err)
+	should.Equal("", conn.activeQuerySql)
+	_, err = QueryOne[int](conn,
+		"SELECT COUNT(*) FROM account_event WHERE entity_id=:entity_id", nil, "entity_id")
+	should.Equal(ErrInvalidInputs, err.(*BindError).Cause)
+	_, err = QueryOne[int](conn, "SELECT COUNT(*) FROM account_event WHERE entity_id=:Eid", nil)
+	should.Equal(ErrUpperCaseParameter, err.(*TranslateError).Cause)
+}
+
+func Test_exec_named(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{}
+	conn := openFakeConn(fake)
+	deleteSql := "DELETE FROM account WHERE entity_id=:entity_id"
+	result, err := ExecNamed(conn, deleteSql, nil, "entity_id", "account1")
+	should.Nil(err)
+	affected, _ := result.RowsAffected()
+	should.Equal(int64(1), affected)
+	should.Equal([]driver.Value{"account1"}, fake.args)
+	_, err = ExecNamed(conn, deleteSql, nil, "event_id", int64(1))
+	should.Equal(ErrArgumentNotFound, err.(*BindError).Cause)
+}
@@ -2,21 +2,22 @@ package dingo
 
 import (
 	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"reflect"
-	"strconv"
-	"strings"
 	"time"
 	"github.com/v2pro/plz/sql"
 )
 
+// DefaultLocation is used to read time text unless Conn or Pool sets its own, UTC if tzdata is missing
 var DefaultLocation *time.Location
 
 func init() {
 	var err error
 	DefaultLocation, err = time.LoadLocation("Asia/Shanghai")
 	if err != nil {
-		panic(err)
+		DefaultLocation = time.UTC
 	}
 }
 
@@ -25,6 +26,9 @@ type Rows struct {
 	obj     driver.Rows
 	columns map[string]sql.ColumnIndex
 	row     []driver.Value
+	// plan of the last type scanned by ScanStruct
+	scanPlanType reflect.Type
+	scanPlan     scanPlan
 }
 
 func (rows *Rows) Columns() []string {
@@ -48,210 +52,213 @@ func (rows *Rows) Close() error {
 	return rows.obj.Close()
 }
 
-type Batch struct {
-	len     int
-	data    map[string]interface{}
-	columns []string
+func (rows *Rows) Next() error {
+	return rows.obj.Next(rows.row)
 }
 
-func NewBatch() *Batch {
-	return &Batch{0, map[string]interface{}{}, nil}
+func (rows *Rows) Get(idx sql.ColumnIndex) interface{} {
+	obj := rows.row[idx]
+	switch val := obj.(type) {
+	case []byte:
+		// copy the bytes to avoid being modified when buffer reused
+		return string(val)
+	}
+	return obj
 }
 
-func (batch *Batch) Len() int {
-	return batch.len
+func (rows *Rows) GetByName(name string) interface{} {
+	return rows.row[rows.C(name)]
 }
 
-func (batch *Batch) Columns() []string {
-	return batch.columns
+// GetString is "" if the column is NULL, number and time are formatted as text
+func (rows *Rows) GetString(idx sql.ColumnIndex) string {
+	val, _, err := rows.GetNullString(idx)
+	if err != nil {
+		panic(err)
+	}
+	return val
 }
 
-func (batch *Batch) GetStringColumn(column string) []string {
-	colData := batch.data[column].([]string)
-	return colData[:batch.len]
+func (rows *Rows) GetByteArray(idx sql.ColumnIndex) []byte {
+	obj := rows.row[idx]
+	switch val := obj.(type) {
+	case nil:
+		return nil
+	case []byte:
+		copied := make([]byte, len(val))
+		copy(copied, val)
+		return copied
+	case string:
+		return []byte(val)
+	}
+	panic(fmt.Sprintf("%v can not convert to []byte", obj))
 }
 
-func (batch *Batch) GetString(row int, column string) string {
-	return batch.GetStringColumn(column)[row]
+// GetTime parses DATE, DATETIME with optional fraction and RFC3339 text in the location of the conn
+func (rows *Rows) GetTime(idx sql.ColumnIndex) time.Time {
+	val, _, err := rows.GetNullTime(idx)
+	if err != nil {
+		panic(err)
+	}
+	return val
 }
 
-func (batch *Batch) GetInt64Column(column string) []int64 {
-	colData := batch.data[column].([]int64)
-	return colData[:batch.len]
+// GetInt64 is 0 if the column is NULL
+func (rows *Rows) GetInt64(idx sql.ColumnIndex) int64 {
+	val, _, err := rows.GetNullInt64(idx)
+	if err != nil {
+		panic(err)
+	}
+	return val
 }
 
-func (batch *Batch) GetInt(row int, column string) int {
-	return int(batch.GetInt64Column(column)[row])
+func (rows *Rows) GetInt(idx sql.ColumnIndex) int {
+	return int(rows.GetInt64(idx))
 }
 
-func (rows *Rows) NextBatch(batch *Batch, maxToRead int) error {
-	batch.len = 0
-	err := rows.obj.Next(rows.row)
-	if err != nil {
-		return err
-	}
-	readers := make([]columnReader, 0, 8)
-	for columnName, columnIndex := range rows.columns {
-		switch rows.row[columnIndex].(type) {
-		case []byte:
-			data, found := batch.data[columnName]
-			if found {
-				typedData, typeOk := data.([]string)
-				if typeOk {
-					if len(typedData) >= maxToRead {
-						readers = append(readers, &byteArrayColumnReader{typedData, int(columnIndex)})
-						continue
-					}
-				}
-			}
-			typedData := make([]string, maxToRead)
-			batch.data[columnName] = typedData
-			readers = append(readers, &byteArrayColumnReader{typedData, int(columnIndex)})
-		case int64:
-			data, found := batch.data[columnName]
-			if found {
-				typedData, typeOk := data.([]int64)
-				if typeOk {
-					if len(typedData) >= maxToRead {
-						readers = append(readers, &int64ColumnReader{typedData, int(columnIndex)})
-						continue
-					}
-				}
-			}
-			typedData := make([]int64, maxToRead)
-			batch.data[columnName] = typedData
-			readers = append(readers, &int64ColumnReader{typedData, int(columnIndex)})
-		default:
-			panic(fmt.Sprintf("unsupported type: %v", reflect.TypeOf(rows.row[columnIndex])))
-		}
-	}
-	for _, reader := range readers {
-		reader.read(rows.row, 0)
+// GetJSON unmarshals the column into dst, dst is not changed if the column is NULL
+func (rows *Rows) GetJSON(idx sql.ColumnIndex, dst interface{}) error {
+	obj := rows.row[idx]
+	if obj == nil {
+		return nil
 	}
-	rowIndex := 1
-	for rowIndex < maxToRead {
-		err = rows.Next()
-		if err != nil {
-			break
-		}
-		for _, reader := range readers {
-			reader.read(rows.row, rowIndex)
+	text, isText := obj.([]byte)
+	if !isText {
+		asString, isString := obj.(string)
+		if !isString {
+			return fmt.Errorf("%v can not convert to json", obj)
 		}
-		rowIndex++
+		text = []byte(asString)
 	}
-	batch.len = rowIndex
-	if err != nil && strings.Contains("EOF", err.Error()) {
-		err = nil
-	}
-	return err
+	return json.Unmarshal(text, dst)
 }
 
-type columnReader interface {
-	read(row []driver.Value, rowIndex int)
+func (rows *Rows) GetUint64(idx sql.ColumnIndex) uint64 {
+	if rows.row[idx] == nil {
+		return 0
+	}
+	val, err := toUint64Value(rows.row[idx])
+	if err != nil {
+		panic(err)
+	}
+	return val
 }
 
-type byteArrayColumnReader struct {
-	data        []string
-	columnIndex int
+func (rows *Rows) GetFloat64(idx sql.ColumnIndex) float64 {
+	if rows.row[idx] == nil {
+		return 0
+	}
+	val, err := toFloat64Value(rows.row[idx])
+	if err != nil {
+		panic(err)
+	}
+	return val
 }
 
-func (reader *byteArrayColumnReader) read(row []driver.Value, rowIndex int) {
-	val := row[reader.columnIndex].([]byte)
-	reader.data[rowIndex] = string(val)
+// GetBool reads TINYINT(1) as 0 or 1, and text true or false
+func (rows *Rows) GetBool(idx sql.ColumnIndex) bool {
+	if rows.row[idx] == nil {
+		return false
+	}
+	val, err := toBoolValue(rows.row[idx])
+	if err != nil {
+		panic(err)
+	}
+	return val
 }
 
-type int64ColumnReader struct {
-	data        []int64
-	columnIndex int
+// GetDecimal reads DECIMAL without losing precision, use FloatString to format it
+func (rows *Rows) GetDecimal(idx sql.ColumnIndex) *big.Rat {
+	if rows.row[idx] == nil {
+		return nil
+	}
+	val, err := toDecimal(rows.row[idx])
+	if err != nil {
+		panic(err)
+	}
+	return val
 }
 
-func (reader *int64ColumnReader) read(row []driver.Value, rowIndex int) {
-	val := row[reader.columnIndex].(int64)
-	reader.data[rowIndex] = val
+// GetNullString is false if the column is NULL, number and time are formatted as text
+func (rows *Rows) GetNullString(idx sql.ColumnIndex) (string, bool, error) {
+	obj := rows.row[idx]
+	if obj == nil {
+		return "", false, nil
+	}
+	return toStringValue(obj), true, nil
 }
 
-func (rows *Rows) Next() error {
-	return rows.obj.Next(rows.row)
+// GetStringOr is defaultValue only if the column is NULL
+func (rows *Rows) GetStringOr(idx sql.ColumnIndex, defaultValue string) (string, error) {
+	val, valid, err := rows.GetNullString(idx)
+	if !valid && err == nil {
+		return defaultValue, nil
+	}
+	return val, err
 }
 
-func (rows *Rows) Get(idx sql.ColumnIndex) interface{} {
+// GetNullInt64 is false if the column is NULL, error if it is not int, empty text is 0
+func (rows *Rows) GetNullInt64(idx sql.ColumnIndex) (int64, bool, error) {
 	obj := rows.row[idx]
-	switch val := obj.(type) {
-	case []byte:
-		// copy the bytes to avoid being modified when buffer reused
-		return string(val)
+	if obj == nil {
+		return 0, false, nil
 	}
-	return obj
+	val, err := toInt64Value(obj)
+	if err != nil {
+		return 0, false, err
+	}
+	return val, true, nil
 }
 
-func (rows *Rows) GetByName(name string) interface{} {
-	return rows.row[rows.C(name)]
+// GetInt64Or is defaultValue only if the column is NULL
+func (rows *Rows) GetInt64Or(idx sql.ColumnIndex, defaultValue int64) (int64, error) {
+	val, valid, err := rows.GetNullInt64(idx)
+	if !valid && err == nil {
+		return defaultValue, nil
+	}
+	return val, err
 }
 
-func (rows *Rows) GetString(idx sql.ColumnIndex) string {
+// GetNullFloat64 is false if the column is NULL, error if it is not number, empty text is 0
+func (rows *Rows) GetNullFloat64(idx sql.ColumnIndex) (float64, bool, error) {
 	obj := rows.row[idx]
-	switch val := obj.(type) {
-	case []byte:
-		return string(val)
-	case string:
-		return val
+	if obj == nil {
+		return 0, false, nil
+	}
+	val, err := toFloat64Value(obj)
+	if err != nil {
+		return 0, false, err
 	}
-	panic(fmt.Sprintf("%v can not convert to string", obj))
+	return val, true, nil
 }
 
-func (rows *Rows) GetByteArray(idx sql.ColumnIndex) []byte {
-	obj := rows.row[idx]
-	switch val := obj.(type) {
-	case []byte:
-		copied := make([]byte, len(val))
-		copy(copied, val)
-		return copied
-	case string:
-		return []byte(val)
+// GetFloat64Or is defaultValue only if the column is NULL
+func (rows *Rows) GetFloat64Or(idx sql.ColumnIndex, defaultValue float64) (float64, error) {
+	val, valid, err := rows.GetNullFloat64(idx)
+	if !valid && err == nil {
+		return defaultValue, nil
 	}
-	panic(fmt.Sprintf("%v can not convert to []byte", obj))
+	return val, err
 }
 
-func (rows *Rows) GetTime(idx sql.ColumnIndex) time.Time {
+// GetNullTime is false if the column is NULL, error if it is not time, text is parsed like GetTime
+func (rows *Rows) GetNullTime(idx sql.ColumnIndex) (time.Time, bool, error) {
 	obj := rows.row[idx]
-	asTime, ok := obj.(time.Time)
-	if ok {
-		return asTime
+	if obj == nil {
+		return time.Time{}, false, nil
 	}
-	val, err := time.ParseInLocation("2006-01-02 15:04:05", rows.GetString(idx), DefaultLocation)
+	val, err := toTime(obj, rows.conn.Location())
 	if err != nil {
-		panic(err)
+		return time.Time{}, false, err
 	}
-	return val
+	return val, true, nil
 }
 
-func (rows *Rows) GetInt64(idx sql.ColumnIndex) int64 {
-	obj := rows.Get(idx)
-	switch val := obj.(type) {
-	case int64:
-		return val
-	case string:
-		if len(val) == 0 {
-			return 0
-		}
-		int64Val, err := strconv.ParseInt(val, 10, 64)
-		if err != nil {
-			panic(err)
-		}
-		return int64Val
-	case []byte:
-		if len(val) == 0 {
-			return 0
-		}
-		int64Val, err := strconv.ParseInt(string(val), 10, 64)
-		if err != nil {
-			panic(err)
-		}
-		return int64Val
+// GetTimeOr is defaultValue only if the column is NULL
+func (rows *Rows) GetTimeOr(idx sql.ColumnIndex, defaultValue time.Time) (time.Time, error) {
+	val, valid, err := rows.GetNullTime(idx)
+	if !valid && err == nil {
+		return defaultValue, nil
 	}
-	panic(fmt.Sprintf("%v can not convert to int", obj))
-}
-
-func (rows *Rows) GetInt(idx sql.ColumnIndex) int {
-	return int(rows.GetInt64(idx))
+	return val, err
 }
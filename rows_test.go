@@ -0,0 +1,160 @@
+package dingo
+
+import (
+	"database/sql/driver"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func queryFakeRows(columns []string, data ...[]driver.Value) *Rows {
+	conn := openFakeConn(&fakeConn{rows: &fakeRows{columns: columns, data: data}})
+	rows, err := conn.TranslateStatement("SELECT * FROM account").Query()
+	if err != nil {
+		panic(err)
+	}
+	return rows.(*Rows)
+}
+
+func Test_get_null(t *testing.T) {
+	should := require.New(t)
+	now := time.Date(2018, 1, 2, 3, 4, 5, 0, DefaultLocation)
+	rows := queryFakeRows([]string{"text", "bytes", "number", "time", "null"},
+		[]driver.Value{"1.5", []byte("2"), int64(3), now, nil})
+	defer rows.Close()
+	should.Nil(rows.Next())
+	val, valid, err := rows.GetNullString(rows.C("number"))
+	should.Nil(err)
+	should.True(valid)
+	should.Equal("3", val)
+	_, valid, err = rows.GetNullString(rows.C("null"))
+	should.Nil(err)
+	should.False(valid)
+	val, err = rows.GetStringOr(rows.C("null"), "default")
+	should.Nil(err)
+	should.Equal("default", val)
+	val, err = rows.GetStringOr(rows.C("bytes"), "default")
+	should.Nil(err)
+	should.Equal("2", val)
+	int64Val, valid, err := rows.GetNullInt64(rows.C("bytes"))
+	should.Nil(err)
+	should.True(valid)
+	should.Equal(int64(2), int64Val)
+	int64Val, err = rows.GetInt64Or(rows.C("null"), -1)
+	should.Nil(err)
+	should.Equal(int64(-1), int64Val)
+	int64Val, err = rows.GetInt64Or(rows.C("number"), -1)
+	should.Nil(err)
+	should.Equal(int64(3), int64Val)
+	float64Val, valid, err := rows.GetNullFloat64(rows.C("text"))
+	should.Nil(err)
+	should.True(valid)
+	should.Equal(1.5, float64Val)
+	float64Val, err = rows.GetFloat64Or(rows.C("number"), 0)
+	should.Nil(err)
+	should.Equal(3.0, float64Val)
+	float64Val, err = rows.GetFloat64Or(rows.C("null"), 0.5)
+	should.Nil(err)
+	should.Equal(0.5, float64Val)
+	timeVal, valid, err := rows.GetNullTime(rows.C("time"))
+	should.Nil(err)
+	should.True(valid)
+	should.Equal(now, timeVal)
+	_, valid, err = rows.GetNullTime(rows.C("null"))
+	should.Nil(err)
+	should.False(valid)
+	timeVal, err = rows.GetTimeOr(rows.C("null"), now)
+	should.Nil(err)
+	should.Equal(now, timeVal)
+}
+
+func Test_get_number(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"amount", "rate", "enabled", "balance", "id"},
+		[]driver.Value{[]byte("12345678901234567890.123456789"), []byte("0.25"), []byte("1"), float64(1.5), []byte("18446744073709551615")},
+		[]driver.Value{int64(7), float64(2), int64(0), int64(-3), uint64(1)})
+	defer rows.Close()
+	should.Nil(rows.Next())
+	should.Equal("12345678901234567890.123456789", rows.GetDecimal(rows.C("amount")).FloatString(9))
+	should.Equal(0.25, rows.GetFloat64(rows.C("rate")))
+	should.True(rows.GetBool(rows.C("enabled")))
+	should.Equal("1.50", rows.GetDecimal(rows.C("balance")).FloatString(2))
+	should.Equal(uint64(18446744073709551615), rows.GetUint64(rows.C("id")))
+	should.Nil(rows.Next())
+	should.Equal("7", rows.GetDecimal(rows.C("amount")).RatString())
+	should.Equal(2.0, rows.GetFloat64(rows.C("rate")))
+	should.False(rows.GetBool(rows.C("enabled")))
+	should.Equal(uint64(1), rows.GetUint64(rows.C("id")))
+	should.Equal(int64(2), rows.GetInt64(rows.C("rate")))
+	should.Equal(int64(1), rows.GetInt64(rows.C("id")))
+	should.Panics(func() {
+		rows.GetUint64(rows.C("balance"))
+	})
+}
+
+func Test_get_conversion_error(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"rate", "id", "text", "null"},
+		[]driver.Value{float64(1.5), uint64(18446744073709551615), []byte("x"), nil},
+		[]driver.Value{float64(2), uint64(3), []byte("2018-01-02"), nil})
+	defer rows.Close()
+	should.Nil(rows.Next())
+	should.Equal("", rows.GetString(rows.C("null")))
+	should.Equal(int64(0), rows.GetInt64(rows.C("null")))
+	should.True(rows.GetTime(rows.C("null")).IsZero())
+	int64Val, err := rows.GetInt64Or(rows.C("rate"), -1)
+	should.NotNil(err)
+	should.Equal(int64(0), int64Val)
+	_, valid, err := rows.GetNullInt64(rows.C("id"))
+	should.NotNil(err)
+	should.False(valid)
+	_, _, err = rows.GetNullTime(rows.C("text"))
+	should.NotNil(err)
+	should.Panics(func() {
+		rows.GetInt64(rows.C("rate"))
+	})
+	should.Panics(func() {
+		rows.GetTime(rows.C("text"))
+	})
+	// conversion error of one cell does not stop the iteration
+	should.Nil(rows.Next())
+	should.Equal(int64(2), rows.GetInt64(rows.C("rate")))
+	int64Val, valid, err = rows.GetNullInt64(rows.C("id"))
+	should.Nil(err)
+	should.True(valid)
+	should.Equal(int64(3), int64Val)
+	should.Equal(2018, rows.GetTime(rows.C("text")).Year())
+}
+
+func Test_get_time(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"date", "datetime", "datetime6", "rfc3339", "local"},
+		[]driver.Value{[]byte("2018-01-02"), []byte("2018-01-02 03:04:05"), []byte("2018-01-02 03:04:05.123456"),
+			"2018-01-02T03:04:05+08:00", "2018-01-02T03:04:05"})
+	defer rows.Close()
+	location := time.FixedZone("UTC-5", -5*3600)
+	rows.conn.SetLocation(location)
+	should.Nil(rows.Next())
+	should.Equal(time.Date(2018, 1, 2, 0, 0, 0, 0, location), rows.GetTime(rows.C("date")))
+	should.Equal(time.Date(2018, 1, 2, 3, 4, 5, 0, location), rows.GetTime(rows.C("datetime")))
+	should.Equal(time.Date(2018, 1, 2, 3, 4, 5, 123456000, location), rows.GetTime(rows.C("datetime6")))
+	should.True(time.Date(2018, 1, 1, 19, 4, 5, 0, time.UTC).Equal(rows.GetTime(rows.C("rfc3339"))))
+	should.Equal(time.Date(2018, 1, 2, 3, 4, 5, 0, location), rows.GetTime(rows.C("local")))
+	rows.conn.SetLocation(nil)
+	should.Equal(DefaultLocation, rows.GetTime(rows.C("datetime")).Location())
+}
+
+func Test_get_JSON(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"state", "null"}, []driver.Value{[]byte(`{"balance":1}`), nil})
+	defer rows.Close()
+	should.Nil(rows.Next())
+	state := map[string]int{"previous": 1}
+	should.Nil(rows.GetJSON(rows.C("null"), &state))
+	should.Equal(map[string]int{"previous": 1}, state)
+	state = nil
+	should.Nil(rows.GetJSON(rows.C("state"), &state))
+	should.Equal(map[string]int{"balance": 1}, state)
+	var invalid []int
+	should.NotNil(rows.GetJSON(rows.C("state"), &invalid))
+}
@@ -0,0 +1,369 @@
+package dingo
+
+import (
+	gosql "database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+scan row into struct by tag db:"entity_id",
+the column to field plan is cached per query columns and struct type
+*/
+
+type scanPlanKey struct {
+	typ     reflect.Type
+	columns string
+}
+
+// scanPlan is the field index of each column, nil if the column is not scanned
+type scanPlan [][]int
+
+var scanPlans = &sync.Map{}
+
+func (rows *Rows) getScanPlan(typ reflect.Type) scanPlan {
+	if rows.scanPlanType == typ {
+		return rows.scanPlan
+	}
+	columns := rows.Columns()
+	key := scanPlanKey{typ, strings.Join(columns, ",")}
+	plan, found := scanPlans.Load(key)
+	if !found {
+		fieldIndices := map[string][]int{}
+		for _, fieldPlan := range getFieldPlans(typ) {
+			fieldIndices[fieldPlan.name] = fieldPlan.index
+		}
+		newPlan := make(scanPlan, len(columns))
+		for column, columnIndex := range rows.columns {
+			newPlan[columnIndex] = fieldIndices[column]
+		}
+		plan, _ = scanPlans.LoadOrStore(key, newPlan)
+	}
+	rows.scanPlanType = typ
+	rows.scanPlan = plan.(scanPlan)
+	return rows.scanPlan
+}
+
+// ScanStruct copies the current row into the struct pointed by dst, column without field is skipped
+func (rows *Rows) ScanStruct(dst interface{}) error {
+	ptr := reflect.ValueOf(dst)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("ScanStruct expects pointer to struct, got %v", reflect.TypeOf(dst))
+	}
+	return rows.scanStruct(ptr.Elem())
+}
+
+func (rows *Rows) scanStruct(obj reflect.Value) error {
+	for columnIndex, fieldIndex := range rows.getScanPlan(obj.Type()) {
+		if fieldIndex == nil {
+			continue
+		}
+		err := setField(obj.FieldByIndex(fieldIndex), rows.row[columnIndex], rows.conn.Location())
+		if err != nil {
+			return fmt.Errorf("scan column %v: %s", rows.Columns()[columnIndex], err.Error())
+		}
+	}
+	return nil
+}
+
+// ScanAll reads the remaining rows and appends them to the slice pointed by dst, []T or []*T
+func (rows *Rows) ScanAll(dst interface{}) error {
+	ptr := reflect.ValueOf(dst)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("ScanAll expects pointer to slice, got %v", reflect.TypeOf(dst))
+	}
+	slice := ptr.Elem()
+	elemType := slice.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("ScanAll expects slice of struct, got %v", slice.Type())
+	}
+	for {
+		err := rows.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		elem := reflect.New(elemType)
+		err = rows.scanStruct(elem.Elem())
+		if err != nil {
+			return err
+		}
+		if isPtr {
+			slice.Set(reflect.Append(slice, elem))
+		} else {
+			slice.Set(reflect.Append(slice, elem.Elem()))
+		}
+	}
+}
+
+var scannerType = reflect.TypeOf((*gosql.Scanner)(nil)).Elem()
+var timeType = reflect.TypeOf(time.Time{})
+var ratType = reflect.TypeOf(big.Rat{})
+
+// setField converts the driver value to the field type, NULL sets the zero value, time text is read in loc
+func setField(field reflect.Value, val driver.Value, loc *time.Location) error {
+	if field.CanAddr() && field.Addr().Type().Implements(scannerType) {
+		return field.Addr().Interface().(gosql.Scanner).Scan(val)
+	}
+	if val == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if field.Kind() == reflect.Ptr {
+		elem := reflect.New(field.Type().Elem())
+		err := setField(elem.Elem(), val, loc)
+		if err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+	if field.Type() == ratType {
+		decimal, err := toDecimal(val)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(decimal).Elem())
+		return nil
+	}
+	if field.Type() == timeType {
+		asTime, err := toTime(val, loc)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(asTime))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(toStringValue(val))
+		return nil
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.Uint8 {
+			break
+		}
+		switch typedVal := val.(type) {
+		case []byte:
+			copied := make([]byte, len(typedVal))
+			copy(copied, typedVal)
+			field.SetBytes(copied)
+			return nil
+		case string:
+			field.SetBytes([]byte(typedVal))
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		int64Val, err := toInt64Value(val)
+		if err != nil {
+			return err
+		}
+		if field.OverflowInt(int64Val) {
+			return fmt.Errorf("%v overflows %v", val, field.Type())
+		}
+		field.SetInt(int64Val)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		uint64Val, err := toUint64Value(val)
+		if err != nil {
+			return err
+		}
+		if field.OverflowUint(uint64Val) {
+			return fmt.Errorf("%v overflows %v", val, field.Type())
+		}
+		field.SetUint(uint64Val)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		float64Val, err := toFloat64Value(val)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(float64Val)
+		return nil
+	case reflect.Bool:
+		boolVal, err := toBoolValue(val)
+		if err != nil {
+			return err
+		}
+		field.SetBool(boolVal)
+		return nil
+	}
+	return fmt.Errorf("%v can not convert to %v", val, field.Type())
+}
+
+func textOf(val driver.Value) (string, bool) {
+	switch typedVal := val.(type) {
+	case []byte:
+		return string(typedVal), true
+	case string:
+		return typedVal, true
+	}
+	return "", false
+}
+
+func toStringValue(val driver.Value) string {
+	switch typedVal := val.(type) {
+	case []byte:
+		return string(typedVal)
+	case string:
+		return typedVal
+	case time.Time:
+		return typedVal.Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprintf("%v", val)
+}
+
+func toInt64Value(val driver.Value) (int64, error) {
+	switch typedVal := val.(type) {
+	case int64:
+		return typedVal, nil
+	case uint64:
+		if typedVal > math.MaxInt64 {
+			return 0, fmt.Errorf("%v overflows int64", val)
+		}
+		return int64(typedVal), nil
+	case float64:
+		// float64(math.MaxInt64) rounds up to 2^63, which is out of range
+		if typedVal != math.Trunc(typedVal) || typedVal < math.MinInt64 || typedVal >= math.MaxInt64 {
+			return 0, fmt.Errorf("%v can not convert to int", val)
+		}
+		return int64(typedVal), nil
+	case bool:
+		if typedVal {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	text, isText := textOf(val)
+	if !isText {
+		return 0, fmt.Errorf("%v can not convert to int", val)
+	}
+	if len(text) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseInt(text, 10, 64)
+}
+
+func toUint64Value(val driver.Value) (uint64, error) {
+	switch typedVal := val.(type) {
+	case int64:
+		if typedVal < 0 {
+			return 0, fmt.Errorf("%v can not convert to uint", val)
+		}
+		return uint64(typedVal), nil
+	case uint64:
+		return typedVal, nil
+	}
+	text, isText := textOf(val)
+	if !isText {
+		return 0, fmt.Errorf("%v can not convert to uint", val)
+	}
+	if len(text) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseUint(text, 10, 64)
+}
+
+func toFloat64Value(val driver.Value) (float64, error) {
+	switch typedVal := val.(type) {
+	case float64:
+		return typedVal, nil
+	case float32:
+		return float64(typedVal), nil
+	case int64:
+		return float64(typedVal), nil
+	case uint64:
+		return float64(typedVal), nil
+	}
+	text, isText := textOf(val)
+	if !isText {
+		return 0, fmt.Errorf("%v can not convert to float", val)
+	}
+	if len(text) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseFloat(text, 64)
+}
+
+func toBoolValue(val driver.Value) (bool, error) {
+	switch typedVal := val.(type) {
+	case bool:
+		return typedVal, nil
+	case int64:
+		return typedVal != 0, nil
+	case uint64:
+		return typedVal != 0, nil
+	}
+	text, isText := textOf(val)
+	if !isText {
+		return false, fmt.Errorf("%v can not convert to bool", val)
+	}
+	switch text {
+	case "", "0", "false", "FALSE", "f":
+		return false, nil
+	case "1", "true", "TRUE", "t":
+		return true, nil
+	}
+	return false, fmt.Errorf("%v can not convert to bool", val)
+}
+
+// toDecimal keeps every digit of the DECIMAL text, float is converted exactly as its binary value
+func toDecimal(val driver.Value) (*big.Rat, error) {
+	switch typedVal := val.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(typedVal), nil
+	case uint64:
+		return new(big.Rat).SetUint64(typedVal), nil
+	case float64:
+		decimal := new(big.Rat)
+		if decimal.SetFloat64(typedVal) == nil {
+			return nil, fmt.Errorf("%v can not convert to decimal", val)
+		}
+		return decimal, nil
+	}
+	text, isText := textOf(val)
+	if !isText {
+		return nil, fmt.Errorf("%v can not convert to decimal", val)
+	}
+	decimal, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, fmt.Errorf("%v can not convert to decimal", val)
+	}
+	return decimal, nil
+}
+
+// toTime reads DATE, DATETIME(6) and RFC3339, text without zone is in loc
+func toTime(val driver.Value, loc *time.Location) (time.Time, error) {
+	if asTime, isTime := val.(time.Time); isTime {
+		return asTime, nil
+	}
+	text, isText := textOf(val)
+	if !isText {
+		return time.Time{}, fmt.Errorf("%v can not convert to time", val)
+	}
+	switch {
+	case len(text) == len("2006-01-02"):
+		return time.ParseInLocation("2006-01-02", text, loc)
+	case len(text) > 10 && text[10] == 'T':
+		asTime, err := time.Parse(time.RFC3339Nano, text)
+		if err == nil {
+			return asTime, nil
+		}
+		return time.ParseInLocation("2006-01-02T15:04:05", text, loc)
+	}
+	// fraction after seconds is accepted even if not in layout
+	return time.ParseInLocation("2006-01-02 15:04:05", text, loc)
+}
@@ -0,0 +1,96 @@
+package dingo
+
+import (
+	"database/sql/driver"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+)
+
+func Test_scan_struct(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{rows: &fakeRows{
+		columns: []string{"entity_id", "event_id", "unknown", "state"},
+		data: [][]driver.Value{
+			{[]byte("account1"), int64(1), int64(0), []byte("{}")},
+			{[]byte("account2"), []byte("2"), nil, nil},
+		},
+	}}
+	conn := openFakeConn(fake)
+	stmt := conn.TranslateStatement("SELECT * FROM account_event")
+	defer stmt.Close()
+	query, err := stmt.Query()
+	should.Nil(err)
+	defer query.Close()
+	rows := query.(*Rows)
+	should.Nil(rows.Next())
+	event := accountEvent{Ignored: "keep"}
+	should.Nil(rows.ScanStruct(&event))
+	should.Equal("account1", event.EntityId)
+	should.Equal(int32(1), event.EventId)
+	should.Equal("keep", event.Ignored)
+	should.Equal("{}", *event.State)
+	should.Nil(rows.Next())
+	should.Nil(rows.ScanStruct(&event))
+	should.Equal("account2", event.EntityId)
+	should.Equal(int32(2), event.EventId)
+	should.Nil(event.State)
+	should.NotNil(rows.ScanStruct(event))
+}
+
+func Test_scan_all(t *testing.T) {
+	should := require.New(t)
+	fake := &fakeConn{rows: &fakeRows{
+		columns: []string{"entity_id", "event_id"},
+		data: [][]driver.Value{
+			{[]byte("account1"), int64(1)},
+			{[]byte("account2"), int64(2)},
+		},
+	}}
+	conn := openFakeConn(fake)
+	stmt := conn.TranslateStatement("SELECT * FROM account_event")
+	defer stmt.Close()
+	query, err := stmt.Query()
+	should.Nil(err)
+	rows := query.(*Rows)
+	events := []*accountEvent{}
+	should.Nil(rows.ScanAll(&events))
+	should.Nil(rows.Close())
+	should.Equal(2, len(events))
+	should.Equal("account2", events[1].EntityId)
+	should.Equal(int32(2), events[1].EventId)
+	fake.rows.data = [][]driver.Value{{[]byte("account3"), []byte("x")}}
+	query, err = stmt.Query()
+	should.Nil(err)
+	rows = query.(*Rows)
+	values := []accountEvent{}
+	should.NotNil(rows.ScanAll(&values))
+	should.Nil(rows.Close())
+}
+
+func Test_scan_decimal(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"amount"}, []driver.Value{[]byte("0.10")})
+	defer rows.Close()
+	should.Nil(rows.Next())
+	ledger := struct {
+		Amount *big.Rat `db:"amount"`
+	}{}
+	should.Nil(rows.ScanStruct(&ledger))
+	should.Equal("1/10", ledger.Amount.RatString())
+}
+
+func Test_scan_overflow(t *testing.T) {
+	should := require.New(t)
+	rows := queryFakeRows([]string{"event_id", "version"}, []driver.Value{int64(1) << 40, []byte("256")})
+	defer rows.Close()
+	should.Nil(rows.Next())
+	event := accountEvent{}
+	should.NotNil(rows.ScanStruct(&event))
+	should.Equal(int32(0), event.EventId)
+	version := struct {
+		Version uint8 `db:"version"`
+	}{}
+	should.NotNil(rows.ScanStruct(&version))
+	should.Equal(uint8(0), version.Version)
+}
@@ -2,7 +2,10 @@ package dingo
 
 import (
 	"bytes"
+	"database/sql/driver"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"unicode"
 	"unsafe"
@@ -10,72 +13,310 @@ import (
 )
 
 /*
-translate named parameter into string argument (%v) or normal argument (?)
-normal argument only support mysql syntax ? yet
+translate named parameter into string argument (%v) or normal argument (? or $1)
+normal argument is rendered by the dialect, mysql syntax ? by default
 */
 
 const stateNormal = 0
 const stateInVar = 1
 const stateInSingleQuote = 5 // '
 const stateInDoubleQuote = 6 // "
+const stateInBacktick = 7     // `
+const stateInLineComment = 8  // --
+const stateInBlockComment = 9 // /*
 
 type TranslatedSql struct {
 	sql             string
 	paramMap        map[string][]int
 	strParamCount   int
 	totalParamCount int
+	strParamNames   []string
+	orderByColumns  map[string][]string
+	dialect         Dialect
+	// parameters changing the sql by their bound values are expanded
+	// by re-translating the template with the bindShape
+	shapeParams []string
+	template    string
+	columns     []interface{}
+	// columns marked as JSON in the column groups
+	jsonParams map[string]bool
+	// VALUES rows of BATCH_INSERT_, nil if not used
+	batchInsert *batchInsert
+}
+
+// batchInsert locates the VALUES rows, column i of row j is at base+j*columnsCount+i
+type batchInsert struct {
+	base         int
+	columnsCount int
+	rowsCount    int
 }
 
 func NewTranslatedSql(sql string, argMap map[string][]int, strParamCount int, totalParamCount int) *TranslatedSql {
-	return &TranslatedSql{sql, argMap, strParamCount, totalParamCount}
+	return &TranslatedSql{sql, argMap, strParamCount, totalParamCount, nil, nil, MySQL, nil, "", nil, nil, nil}
+}
+
+func (translatedSql *TranslatedSql) strParamName(pos int) string {
+	if pos < len(translatedSql.strParamNames) {
+		return translatedSql.strParamNames[pos]
+	}
+	return ""
+}
+
+// isStrParam tells STR_ argument from HINT_ argument, STR_ argument should be checked before format
+func (translatedSql *TranslatedSql) isStrParam(pos int) bool {
+	name := translatedSql.strParamName(pos)
+	return name == "" || strings.HasPrefix(name, "STR_")
+}
+
+func (translatedSql *TranslatedSql) Dialect() Dialect {
+	return translatedSql.dialect
+}
+
+// SQL is the translated sql, string arguments are still %v
+func (translatedSql *TranslatedSql) SQL() string {
+	return translatedSql.sql
+}
+
+// ParamNames lists the names of normal arguments, in the order they first appear
+func (translatedSql *TranslatedSql) ParamNames() []string {
+	names := make([]string, 0, len(translatedSql.paramMap))
+	for name, positions := range translatedSql.paramMap {
+		if len(positions) > 0 && positions[0] < translatedSql.strParamCount {
+			continue
+		}
+		names = append(names, name)
+	}
+	translatedSql.sortByPosition(names)
+	return names
+}
+
+// StrParamNames lists the names of string arguments (STR_, HINT_ and ORDER_BY_), in the order they appear
+func (translatedSql *TranslatedSql) StrParamNames() []string {
+	names := make([]string, 0, len(translatedSql.strParamNames))
+	for _, name := range translatedSql.strParamNames {
+		if containsString(names, name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// Positions is where the argument goes in the argument list, string arguments come first
+func (translatedSql *TranslatedSql) Positions(name string) []int {
+	positions := translatedSql.paramMap[name]
+	copied := make([]int, len(positions))
+	copy(copied, positions)
+	return copied
+}
+
+// Unbound lists the names taking position in sql but not bound by the inputs of Stmt.Exec or Stmt.Query
+func (translatedSql *TranslatedSql) Unbound(inputs ...driver.Value) []string {
+	bound := map[string]bool{}
+	collectBound(bound, inputs)
+	unbound := []string{}
+	for name, positions := range translatedSql.paramMap {
+		if len(positions) > 0 && !bound[name] {
+			unbound = append(unbound, name)
+		}
+	}
+	translatedSql.sortByPosition(unbound)
+	return unbound
+}
+
+func collectBound(bound map[string]bool, inputs []driver.Value) {
+	for i, input := range inputs {
+		if batchInsertRow, isBatchInsert := input.([]driver.Value); isBatchInsert {
+			collectBound(bound, batchInsertRow)
+			continue
+		}
+		if i%2 != 0 || i+1 >= len(inputs) {
+			continue
+		}
+		argName, isName := input.(string)
+		if !isName {
+			continue
+		}
+		if argName == "ROW" {
+			if row, isRows := inputs[i+1].(*Rows); isRows {
+				for column := range row.columns {
+					bound[column] = true
+				}
+			}
+			continue
+		}
+		bound[argName] = true
+	}
 }
 
+func (translatedSql *TranslatedSql) sortByPosition(names []string) {
+	sort.Slice(names, func(i, j int) bool {
+		iPositions := translatedSql.paramMap[names[i]]
+		jPositions := translatedSql.paramMap[names[j]]
+		if len(iPositions) == 0 || len(jPositions) == 0 {
+			if len(iPositions) == len(jPositions) {
+				return names[i] < names[j]
+			}
+			return len(jPositions) == 0
+		}
+		return iPositions[0] < jPositions[0]
+	})
+}
+
+func containsString(strs []string, str string) bool {
+	for _, elem := range strs {
+		if elem == str {
+			return true
+		}
+	}
+	return false
+}
+
+// Translate panics with *TranslateError if the sql can not be translated, use TranslateE for sql not known at compile time
 func Translate(sql string, columns ...interface{}) sql.Translated {
-	columnGroups := spitIntoGroups(columns)
+	translatedSql, err := TranslateE(sql, columns...)
+	if err != nil {
+		panic(err)
+	}
+	return translatedSql
+}
+
+// TranslateE is Translate returning *TranslateError instead of panic
+func TranslateE(sql string, columns ...interface{}) (*TranslatedSql, error) {
+	columnGroups, dialect, shape, err := spitIntoGroups(columns)
+	if err != nil {
+		err.(*TranslateError).Sql = sql
+		return nil, err
+	}
 	sqlAsBytes := *(*[]byte)(unsafe.Pointer(&sql))
 	buf := bytes.NewBuffer(make([]byte, 0, len(sql)))
 	state := stateNormal
 	tempVarName := bytes.NewBuffer(make([]byte, 0, 10))
+	varStart := 0
 	paramMap := newParamMap()
+	paramMap.dialect = dialect
+	paramMap.shape = shape
 	strParamMap := newParamMap()
+	// content of /*IF name*/ ... /*END*/ is dropped if name is not bound
+	conditions := []bool{}
+	dropDepth := 0
+	dropped := bytes.NewBuffer(nil)
 	for i := 0; i < len(sqlAsBytes); i++ {
 		c := sqlAsBytes[i]
+		out := buf
+		if dropDepth > 0 {
+			out = dropped
+		}
 		switch state {
 		case stateInVar:
-			if ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c == '_' || c == '-' || ('0' <= c && c <= '9') {
+			isLineComment := c == '-' && i+1 < len(sqlAsBytes) && sqlAsBytes[i+1] == '-'
+			if !isLineComment && (('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c == '_' || c == '-' || ('0' <= c && c <= '9')) {
 				tempVarName.WriteByte(c)
 			} else {
 				varName := tempVarName.String()
-				addVar(varName, columnGroups, buf, paramMap, strParamMap)
-				buf.WriteByte(c)
+				if dropDepth > 0 {
+					paramMap.addDroppedParameter(varName, columnGroups)
+				} else {
+					err = addVar(varName, columnGroups, buf, paramMap, strParamMap)
+					if err != nil {
+						return nil, &TranslateError{err, varName, varStart, sql}
+					}
+				}
 				state = stateNormal
+				// the byte ending the var might start quote or comment
+				i--
 			}
 		case stateInSingleQuote:
-			buf.WriteByte(c)
-			if c == '\\' {
+			writeTemplateByte(out, c)
+			if c == '\\' && i+1 < len(sqlAsBytes) {
 				i++
-				buf.WriteByte(sqlAsBytes[i])
+				writeTemplateByte(out, sqlAsBytes[i])
 			} else if c == '\'' {
 				state = stateNormal
 			}
 		case stateInDoubleQuote:
-			buf.WriteByte(c)
-			if c == '\\' {
+			writeTemplateByte(out, c)
+			if c == '\\' && i+1 < len(sqlAsBytes) {
 				i++
-				buf.WriteByte(sqlAsBytes[i])
+				writeTemplateByte(out, sqlAsBytes[i])
 			} else if c == '"' {
 				state = stateNormal
 			}
+		case stateInBacktick:
+			writeTemplateByte(out, c)
+			if c == '`' {
+				state = stateNormal
+			}
+		case stateInLineComment:
+			writeTemplateByte(out, c)
+			if c == '\n' {
+				state = stateNormal
+			}
+		case stateInBlockComment:
+			writeTemplateByte(out, c)
+			if c == '*' && i+1 < len(sqlAsBytes) && sqlAsBytes[i+1] == '/' {
+				i++
+				out.WriteByte('/')
+				state = stateNormal
+			}
 		default:
+			if c == '/' && i+1 < len(sqlAsBytes) && sqlAsBytes[i+1] == '*' {
+				directive, name, directiveEnd := parseDirective(sql, i)
+				if directive == "IF" {
+					paramMap.shapeParams[name] = true
+					drop := dropDepth > 0 || !paramMap.shape.boundParams[name]
+					conditions = append(conditions, drop)
+					if drop {
+						dropDepth++
+					}
+					i = directiveEnd
+					continue
+				}
+				if directive == "END" {
+					if len(conditions) == 0 {
+						return nil, &TranslateError{ErrUnbalancedCondition, "END", i, sql}
+					}
+					if conditions[len(conditions)-1] {
+						dropDepth--
+					}
+					conditions = conditions[:len(conditions)-1]
+					i = directiveEnd
+					continue
+				}
+			}
 			if c == ':' {
-				state = stateInVar
-				tempVarName.Reset()
+				if i+1 == len(sqlAsBytes) {
+					return nil, &TranslateError{ErrMissingParameterName, "", i, sql}
+				}
+				next := sqlAsBytes[i+1]
+				if next == ':' {
+					// postgres type cast x::int
+					i++
+					out.WriteString("::")
+				} else if isVarStart(next) {
+					state = stateInVar
+					varStart = i
+					tempVarName.Reset()
+				} else {
+					// mysql assignment @v := 1, or other literal colon
+					writeTemplateByte(out, c)
+				}
 			} else {
-				buf.WriteByte(c)
+				writeTemplateByte(out, c)
 				if c == '\'' {
 					state = stateInSingleQuote
 				} else if c == '"' {
 					state = stateInDoubleQuote
+				} else if c == '`' {
+					state = stateInBacktick
+				} else if c == '-' && i+1 < len(sqlAsBytes) && sqlAsBytes[i+1] == '-' {
+					i++
+					out.WriteByte('-')
+					state = stateInLineComment
+				} else if c == '/' && i+1 < len(sqlAsBytes) && sqlAsBytes[i+1] == '*' {
+					i++
+					out.WriteByte('*')
+					state = stateInBlockComment
 				}
 			}
 
@@ -83,46 +324,276 @@ func Translate(sql string, columns ...interface{}) sql.Translated {
 	}
 	if state == stateInVar {
 		varName := tempVarName.String()
-		addVar(varName, columnGroups, buf, paramMap, strParamMap)
+		if dropDepth > 0 {
+			paramMap.addDroppedParameter(varName, columnGroups)
+		} else {
+			err = addVar(varName, columnGroups, buf, paramMap, strParamMap)
+			if err != nil {
+				return nil, &TranslateError{err, varName, varStart, sql}
+			}
+		}
+	}
+	if len(conditions) > 0 {
+		return nil, &TranslateError{ErrUnbalancedCondition, "IF", len(sql), sql}
 	}
 	strParamCount := strParamMap.currentPos
+	strParamNames := make([]string, strParamCount)
+	for name, positions := range strParamMap.paramMap {
+		for _, pos := range positions {
+			strParamNames[pos] = name
+		}
+	}
 	strParamMap.merge(paramMap)
-	return &TranslatedSql{buf.String(), strParamMap.paramMap, strParamCount, paramMap.currentPos + strParamCount}
+	shapeParams := make([]string, 0, len(paramMap.shapeParams))
+	for name := range paramMap.shapeParams {
+		shapeParams = append(shapeParams, name)
+	}
+	sort.Strings(shapeParams)
+	var shiftedBatchInsert *batchInsert
+	if paramMap.batchInsert != nil {
+		shiftedBatchInsert = &batchInsert{paramMap.batchInsert.base + strParamCount,
+			paramMap.batchInsert.columnsCount, paramMap.batchInsert.rowsCount}
+	}
+	jsonParams := map[string]bool{}
+	for _, group := range columnGroups {
+		for _, column := range group.JSONColumns {
+			jsonParams[column] = true
+		}
+	}
+	translatedText := buf.String()
+	if strParamCount == 0 {
+		// not formatted, % is kept as it is
+		translatedText = strings.Replace(translatedText, "%%", "%", -1)
+	}
+	return &TranslatedSql{translatedText, strParamMap.paramMap, strParamCount, paramMap.currentPos + strParamCount,
+		strParamNames, strParamMap.orderByColumns, dialect, shapeParams, sql, append([]interface{}{}, columns...),
+		jsonParams, shiftedBatchInsert}, nil
+}
+
+// writeTemplateByte doubles %, as the sql with STR_, HINT_ or ORDER_BY_ is formatted by fmt.Sprintf
+func writeTemplateByte(buf *bytes.Buffer, c byte) {
+	if c == '%' {
+		buf.WriteByte('%')
+	}
+	buf.WriteByte(c)
+}
+
+// parseDirective recognizes /*IF name*/ and /*END*/ starting at i, returns the index of the closing slash
+func parseDirective(sql string, i int) (string, string, int) {
+	commentEnd := strings.Index(sql[i+2:], "*/")
+	if commentEnd == -1 {
+		return "", "", 0
+	}
+	directiveEnd := i + 2 + commentEnd + 1
+	content := strings.TrimSpace(sql[i+2 : i+2+commentEnd])
+	if content == "END" {
+		return "END", "", directiveEnd
+	}
+	if !strings.HasPrefix(content, "IF ") {
+		return "", "", 0
+	}
+	name := strings.TrimSpace(content[len("IF "):])
+	if len(name) == 0 || !isVarStart(name[0]) {
+		return "", "", 0
+	}
+	for j := 1; j < len(name); j++ {
+		c := name[j]
+		if !(isVarStart(c) || c == '-' || ('0' <= c && c <= '9')) {
+			return "", "", 0
+		}
+	}
+	return "IF", name, directiveEnd
+}
+
+func isVarStart(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c == '_'
+}
+
+// ColumnGroup is sql.ColumnGroup with the attributes only understood by this package
+type ColumnGroup struct {
+	sql.ColumnGroup
+	// conflict keys of UPSERT_, not updated on duplicate
+	Keys []string
+	// value bound to these columns is marshaled as JSON, unless it is string or []byte already
+	JSONColumns []string
 }
 
-func spitIntoGroups(ungrouped []interface{}) map[string]*sql.ColumnGroup {
-	grouped := map[string]*sql.ColumnGroup{}
-	grouped["COLUMNS"] = &sql.ColumnGroup{"COLUMNS", make([]string, 0), 0}
+func UpsertColumns(group string, keys []string, columns ...string) ColumnGroup {
+	return ColumnGroup{ColumnGroup: sql.ColumnGroup{Group: group, Columns: columns}, Keys: keys}
+}
+
+// JSONColumns is column group marking jsonColumns as JSON, JSONColumns("COLUMNS", []string{"state"}, "entity_id", "state")
+func JSONColumns(group string, jsonColumns []string, columns ...string) ColumnGroup {
+	return ColumnGroup{ColumnGroup: sql.ColumnGroup{Group: group, Columns: columns}, JSONColumns: jsonColumns}
+}
+
+// WithJSON marks the columns as JSON, UpsertColumns("COLUMNS", keys, "entity_id", "state").WithJSON("state")
+func (group ColumnGroup) WithJSON(columns ...string) ColumnGroup {
+	group.JSONColumns = append(append([]string{}, group.JSONColumns...), columns...)
+	return group
+}
+
+func (group *ColumnGroup) isKey(column string) bool {
+	for _, key := range group.Keys {
+		if key == column {
+			return true
+		}
+	}
+	return false
+}
+
+// bindShape is passed along with the columns to translate the sql for the bound values
+type bindShape struct {
+	// IN_ list => number of elements, 1 if not bound
+	listLengths map[string]int
+	// NULLABLE_ column => bound to nil
+	nullColumns map[string]bool
+	// /*IF name*/ => name is bound
+	boundParams map[string]bool
+}
+
+func (shape bindShape) key(shapeParams []string) string {
+	key := make([]byte, 0, 32)
+	for _, name := range shapeParams {
+		key = append(key, name...)
+		if strings.HasPrefix(name, "IN_") {
+			length, found := shape.listLengths[name]
+			if !found {
+				length = 1
+			}
+			key = append(key, '=')
+			key = strconv.AppendInt(key, int64(length), 10)
+		}
+		if shape.nullColumns[name] {
+			key = append(key, "=NULL"...)
+		}
+		if shape.boundParams[name] {
+			key = append(key, '+')
+		}
+		key = append(key, ',')
+	}
+	return string(key)
+}
+
+func spitIntoGroups(ungrouped []interface{}) (map[string]*ColumnGroup, Dialect, bindShape, error) {
+	dialect := MySQL
+	shape := bindShape{}
+	grouped := map[string]*ColumnGroup{}
+	grouped["COLUMNS"] = &ColumnGroup{ColumnGroup: sql.ColumnGroup{"COLUMNS", make([]string, 0), 0}}
 	for _, columnOrGroup := range ungrouped {
 		switch typed := columnOrGroup.(type) {
 		case string:
 			grouped["COLUMNS"].Columns = append(grouped["COLUMNS"].Columns, typed)
 		case sql.ColumnGroup:
+			grouped[typed.Group] = &ColumnGroup{ColumnGroup: typed}
+		case ColumnGroup:
 			grouped[typed.Group] = &typed
+		case Dialect:
+			dialect = typed
+		case bindShape:
+			shape = typed
 		default:
-			panic(fmt.Sprintf("unexpected column argument: %v", columnOrGroup))
+			return nil, dialect, shape, &TranslateError{ErrUnexpectedColumnArgument, fmt.Sprintf("%v", columnOrGroup), -1, ""}
 		}
 	}
-	return grouped
+	return grouped, dialect, shape, nil
 }
 
 type nameToPositions struct {
-	paramMap   map[string][]int
-	currentPos int
+	paramMap    map[string][]int
+	currentPos  int
+	dialect     Dialect
+	shape       bindShape
+	shapeParams map[string]bool
+	// ORDER_BY_ argument => columns allowed
+	orderByColumns map[string][]string
+	batchInsert    *batchInsert
 }
 
 func newParamMap() *nameToPositions {
-	return &nameToPositions{map[string][]int{}, 0}
+	return &nameToPositions{map[string][]int{}, 0, MySQL, bindShape{}, map[string]bool{}, map[string][]string{}, nil}
 }
 
-func (ntp *nameToPositions) addParameter(name string) {
-	positions, existing := ntp.paramMap[name]
-	if existing {
-		ntp.paramMap[name] = append(positions, ntp.currentPos)
-	} else {
-		ntp.paramMap[name] = []int{ntp.currentPos}
+// addParameter returns the position of the parameter,
+// numbered placeholder ($1) reuse the existing position for the same name.
+// name dropped by /*IF*/ or bound to NULL has no position yet
+func (ntp *nameToPositions) addParameter(name string) int {
+	positions := ntp.paramMap[name]
+	if len(positions) > 0 && ntp.dialect == Postgres {
+		return positions[0]
 	}
+	pos := ntp.currentPos
+	ntp.paramMap[name] = append(positions, pos)
+	ntp.currentPos++
+	return pos
+}
+
+// addFreshParameter takes a new position, even if the name is bound already
+func (ntp *nameToPositions) addFreshParameter(name string) int {
+	pos := ntp.currentPos
+	ntp.paramMap[name] = append(ntp.paramMap[name], pos)
 	ntp.currentPos++
+	return pos
+}
+
+// addListParameter takes count positions for the list elements,
+// the list dropped by /*IF*/ has no position to reuse
+func (ntp *nameToPositions) addListParameter(name string, count int) []int {
+	positions := ntp.paramMap[name]
+	if len(positions) > 0 && ntp.dialect == Postgres {
+		return positions
+	}
+	listPositions := make([]int, count)
+	for i := 0; i < count; i++ {
+		listPositions[i] = ntp.currentPos
+		positions = append(positions, ntp.currentPos)
+		ntp.currentPos++
+	}
+	ntp.paramMap[name] = positions
+	return listPositions
+}
+
+// bound by the columns of the group
+var columnGroupVarPrefixes = []string{"BATCH_INSERT_", "INSERT_", "UPSERT_", "NULLABLE_WHERE_", "NULLABLE_OR_",
+	"WHERE_", "OR_", "UPDATE_"}
+
+// bound by the var name itself
+var namedVarPrefixes = []string{"IN_", "STR_", "ORDER_BY_"}
+
+// addDroppedParameter keeps the name bindable, while it is not in the sql.
+// column group var keeps the columns of the group bindable
+func (ntp *nameToPositions) addDroppedParameter(name string, columnGroups map[string]*ColumnGroup) {
+	if !unicode.IsUpper(rune(name[0])) || name == "LIMIT" || name == "OFFSET" {
+		ntp.addDroppedName(name)
+		return
+	}
+	for _, prefix := range namedVarPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			ntp.addDroppedName(name)
+			return
+		}
+	}
+	for _, prefix := range columnGroupVarPrefixes {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if columns, found := columnGroups[name[len(prefix):]]; found {
+			for _, column := range columns.Columns {
+				ntp.addDroppedName(column)
+			}
+		}
+		return
+	}
+}
+
+func (ntp *nameToPositions) addDroppedName(name string) {
+	if _, existing := ntp.paramMap[name]; !existing {
+		ntp.paramMap[name] = []int{}
+	}
+}
+
+func (ntp *nameToPositions) writeParameter(buf *bytes.Buffer, name string) {
+	ntp.dialect.writePlaceholder(buf, ntp.addParameter(name))
 }
 
 func (ntp *nameToPositions) merge(that *nameToPositions) {
@@ -135,50 +606,72 @@ func (ntp *nameToPositions) merge(that *nameToPositions) {
 	}
 }
 
-func addVar(varName string, columnGroups map[string]*sql.ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) {
+func addVar(varName string, columnGroups map[string]*ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) error {
 	if strings.HasPrefix(varName, "BATCH_INSERT_") {
-		addVar_BATCH_INSERT(varName, columnGroups, buf, paramMap, strParamMap)
-		return
+		return addVar_BATCH_INSERT(varName, columnGroups, buf, paramMap, strParamMap)
 	}
 	if strings.HasPrefix(varName, "INSERT_") {
-		addVar_INSERT(varName, columnGroups, buf, paramMap, strParamMap)
-		return
+		return addVar_INSERT(varName, columnGroups, buf, paramMap, strParamMap)
+	}
+	if strings.HasPrefix(varName, "UPSERT_") {
+		return addVar_UPSERT(varName, columnGroups, buf, paramMap, strParamMap)
+	}
+	if strings.HasPrefix(varName, "NULLABLE_WHERE_") {
+		return addVar_NULLABLE_WHERE(varName, columnGroups, buf, paramMap, strParamMap)
+	}
+	if strings.HasPrefix(varName, "NULLABLE_OR_") {
+		return addVar_NULLABLE_OR(varName, columnGroups, buf, paramMap, strParamMap)
+	}
+	if strings.HasPrefix(varName, "WHERE_") {
+		return addVar_WHERE(varName, columnGroups, buf, paramMap, strParamMap)
+	}
+	if strings.HasPrefix(varName, "OR_") {
+		return addVar_OR(varName, columnGroups, buf, paramMap, strParamMap)
 	}
 	if strings.HasPrefix(varName, "UPDATE_") {
-		addVar_UPDATE(varName, columnGroups, buf, paramMap, strParamMap)
-		return
+		return addVar_UPDATE(varName, columnGroups, buf, paramMap, strParamMap)
 	}
 	if strings.HasPrefix(varName, "SELECT_") {
-		addVar_SELECT(varName, columnGroups, buf, paramMap, strParamMap)
-		return
+		return addVar_SELECT(varName, columnGroups, buf, paramMap, strParamMap)
 	}
 	if strings.HasPrefix(varName, "HINT_") {
-		addVar_HINT(varName, columnGroups, buf, paramMap, strParamMap)
-		return
+		return addVar_HINT(varName, columnGroups, buf, paramMap, strParamMap)
+	}
+	if strings.HasPrefix(varName, "IN_") {
+		addVar_IN(varName, buf, paramMap)
+		return nil
+	}
+	if strings.HasPrefix(varName, "ORDER_BY_") {
+		return addVar_ORDER_BY(varName, columnGroups, buf, paramMap, strParamMap)
+	}
+	if varName == "LIMIT" || varName == "OFFSET" {
+		return addVar_LIMIT(varName, columnGroups, buf, paramMap, strParamMap)
 	}
 	if strings.HasPrefix(varName, "STR_") {
 		strParamMap.addParameter(varName)
 		buf.WriteByte('%')
 		buf.WriteByte('v')
-		return
+		return nil
 	}
 	if unicode.IsUpper(rune(varName[0])) {
-		panic("normal parameter should not start with upper case: " + varName)
+		return ErrUpperCaseParameter
 	}
-	paramMap.addParameter(varName)
-	buf.WriteByte('?')
+	paramMap.writeParameter(buf, varName)
+	return nil
 }
 
-func addVar_BATCH_INSERT(varName string, columnGroups map[string]*sql.ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) {
+func addVar_BATCH_INSERT(varName string, columnGroups map[string]*ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) error {
 	group := varName[len("BATCH_INSERT_"):]
 	columns, found := columnGroups[group]
 	if !found {
-		panic(fmt.Sprintf("%v referenced column group not specified: %v", varName, columnGroups))
+		return ErrUnknownColumnGroup
 	}
 	isFirst := true
+	// the first row binds the columns, the other rows take the positions following it
+	base := paramMap.currentPos
 	buf.WriteByte('(')
 	for _, column := range columns.Columns {
-		paramMap.addParameter(column)
+		paramMap.addFreshParameter(column)
 		if isFirst {
 			isFirst = false
 		} else {
@@ -187,6 +680,10 @@ func addVar_BATCH_INSERT(varName string, columnGroups map[string]*sql.ColumnGrou
 		buf.WriteString(column)
 	}
 	buf.WriteString(") VALUES ")
+	if columns.BatchInsertRowsCount > 1 {
+		paramMap.currentPos += (columns.BatchInsertRowsCount - 1) * len(columns.Columns)
+	}
+	paramMap.batchInsert = &batchInsert{base, len(columns.Columns), columns.BatchInsertRowsCount}
 	valuesIsFirst := true
 	for j := 0; j < columns.BatchInsertRowsCount; j++ {
 		if valuesIsFirst {
@@ -202,22 +699,24 @@ func addVar_BATCH_INSERT(varName string, columnGroups map[string]*sql.ColumnGrou
 			} else {
 				buf.WriteString(", ")
 			}
-			buf.WriteByte('?')
+			paramMap.dialect.writePlaceholder(buf, base+j*len(columns.Columns)+i)
 		}
 		buf.WriteByte(')')
 	}
+	return nil
 }
 
-func addVar_INSERT(varName string, columnGroups map[string]*sql.ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) {
+func addVar_INSERT(varName string, columnGroups map[string]*ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) error {
 	group := varName[len("INSERT_"):]
 	columns, found := columnGroups[group]
 	if !found {
-		panic(fmt.Sprintf("%v referenced column group not specified: %v", varName, columnGroups))
+		return ErrUnknownColumnGroup
 	}
 	isFirst := true
+	positions := make([]int, len(columns.Columns))
 	buf.WriteByte('(')
-	for _, column := range columns.Columns {
-		paramMap.addParameter(column)
+	for i, column := range columns.Columns {
+		positions[i] = paramMap.addParameter(column)
 		if isFirst {
 			isFirst = false
 		} else {
@@ -233,20 +732,82 @@ func addVar_INSERT(varName string, columnGroups map[string]*sql.ColumnGroup, buf
 		} else {
 			buf.WriteString(", ")
 		}
-		buf.WriteByte('?')
+		paramMap.dialect.writePlaceholder(buf, positions[i])
 	}
 	buf.WriteByte(')')
+	return nil
 }
 
-func addVar_UPDATE(varName string, columnGroups map[string]*sql.ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) {
+func addVar_UPSERT(varName string, columnGroups map[string]*ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) error {
+	group := varName[len("UPSERT_"):]
+	columns, found := columnGroups[group]
+	if !found {
+		return ErrUnknownColumnGroup
+	}
+	if len(columns.Columns) == 0 {
+		return ErrEmptyColumnGroup
+	}
+	if paramMap.dialect == Postgres && len(columns.Keys) == 0 {
+		return ErrMissingConflictKeys
+	}
+	err := addVar_INSERT("INSERT_"+group, columnGroups, buf, paramMap, strParamMap)
+	if err != nil {
+		return err
+	}
+	if paramMap.dialect == Postgres {
+		buf.WriteString(" ON CONFLICT (")
+		buf.WriteString(Join(columns.Keys...))
+		buf.WriteString(") DO ")
+	} else {
+		buf.WriteString(" ON DUPLICATE KEY ")
+	}
+	isFirst := true
+	for _, column := range columns.Columns {
+		if columns.isKey(column) {
+			continue
+		}
+		if isFirst {
+			isFirst = false
+			if paramMap.dialect == Postgres {
+				buf.WriteString("UPDATE SET ")
+			} else {
+				buf.WriteString("UPDATE ")
+			}
+		} else {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(column)
+		if paramMap.dialect == Postgres {
+			buf.WriteString("=EXCLUDED.")
+			buf.WriteString(column)
+		} else {
+			buf.WriteString("=VALUES(")
+			buf.WriteString(column)
+			buf.WriteByte(')')
+		}
+	}
+	if isFirst && len(columns.Columns) > 0 {
+		// every column is key, nothing to update
+		if paramMap.dialect == Postgres {
+			buf.WriteString("NOTHING")
+		} else {
+			buf.WriteString("UPDATE ")
+			buf.WriteString(columns.Columns[0])
+			buf.WriteByte('=')
+			buf.WriteString(columns.Columns[0])
+		}
+	}
+	return nil
+}
+
+func addVar_UPDATE(varName string, columnGroups map[string]*ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) error {
 	group := varName[len("UPDATE_"):]
 	columns, found := columnGroups[group]
 	if !found {
-		panic(fmt.Sprintf("%v referenced column group not specified: %v", varName, columnGroups))
+		return ErrUnknownColumnGroup
 	}
 	isFirst := true
 	for _, column := range columns.Columns {
-		paramMap.addParameter(column)
 		if isFirst {
 			isFirst = false
 		} else {
@@ -254,24 +815,126 @@ func addVar_UPDATE(varName string, columnGroups map[string]*sql.ColumnGroup, buf
 		}
 		buf.WriteString(column)
 		buf.WriteByte('=')
-		buf.WriteByte('?')
+		paramMap.writeParameter(buf, column)
+	}
+	return nil
+}
+
+func addVar_WHERE(varName string, columnGroups map[string]*ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) error {
+	columns, found := columnGroups[varName[len("WHERE_"):]]
+	if !found {
+		return ErrUnknownColumnGroup
 	}
+	return writeConditions(columns, " AND ", false, buf, paramMap)
 }
 
-func addVar_SELECT(varName string, columnGroups map[string]*sql.ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) {
+func addVar_OR(varName string, columnGroups map[string]*ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) error {
+	columns, found := columnGroups[varName[len("OR_"):]]
+	if !found {
+		return ErrUnknownColumnGroup
+	}
+	buf.WriteByte('(')
+	if err := writeConditions(columns, " OR ", false, buf, paramMap); err != nil {
+		return err
+	}
+	buf.WriteByte(')')
+	return nil
+}
+
+func addVar_NULLABLE_WHERE(varName string, columnGroups map[string]*ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) error {
+	columns, found := columnGroups[varName[len("NULLABLE_WHERE_"):]]
+	if !found {
+		return ErrUnknownColumnGroup
+	}
+	return writeConditions(columns, " AND ", true, buf, paramMap)
+}
+
+func addVar_NULLABLE_OR(varName string, columnGroups map[string]*ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) error {
+	columns, found := columnGroups[varName[len("NULLABLE_OR_"):]]
+	if !found {
+		return ErrUnknownColumnGroup
+	}
+	buf.WriteByte('(')
+	if err := writeConditions(columns, " OR ", true, buf, paramMap); err != nil {
+		return err
+	}
+	buf.WriteByte(')')
+	return nil
+}
+
+// writeConditions renders a=? AND b=?, nullable renders a IS NULL if a is bound to nil.
+// empty group is an error, as nothing would be left of WHERE and () is invalid sql
+func writeConditions(columns *ColumnGroup, separator string, nullable bool, buf *bytes.Buffer, paramMap *nameToPositions) error {
+	if len(columns.Columns) == 0 {
+		return ErrEmptyColumnGroup
+	}
+	for i, column := range columns.Columns {
+		if i != 0 {
+			buf.WriteString(separator)
+		}
+		buf.WriteString(column)
+		if nullable {
+			paramMap.shapeParams[column] = true
+			if paramMap.shape.nullColumns[column] {
+				buf.WriteString(" IS NULL")
+				// bindable without position, a later :column still takes one
+				paramMap.addDroppedName(column)
+				continue
+			}
+		}
+		buf.WriteByte('=')
+		paramMap.writeParameter(buf, column)
+	}
+	return nil
+}
+
+// addVar_ORDER_BY renders ORDER BY a DESC when formatted, the column is checked against the column group
+func addVar_ORDER_BY(varName string, columnGroups map[string]*ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) error {
+	columns, found := columnGroups[varName[len("ORDER_BY_"):]]
+	if !found {
+		return ErrUnknownColumnGroup
+	}
+	strParamMap.addParameter(varName)
+	strParamMap.orderByColumns[varName] = columns.Columns
+	buf.WriteByte('%')
+	buf.WriteByte('v')
+	return nil
+}
+
+// addVar_LIMIT renders LIMIT ? or OFFSET ?, dropped if not bound
+func addVar_LIMIT(varName string, columnGroups map[string]*ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) error {
+	paramMap.shapeParams[varName] = true
+	if !paramMap.shape.boundParams[varName] {
+		if _, existing := paramMap.paramMap[varName]; !existing {
+			paramMap.paramMap[varName] = []int{}
+		}
+		return nil
+	}
+	if varName == "OFFSET" && paramMap.dialect == MySQL && !paramMap.shape.boundParams["LIMIT"] {
+		// mysql does not accept OFFSET without LIMIT, the max row count is from mysql manual
+		buf.WriteString("LIMIT 18446744073709551615 ")
+	}
+	buf.WriteString(varName)
+	buf.WriteByte(' ')
+	paramMap.writeParameter(buf, varName)
+	return nil
+}
+
+func addVar_SELECT(varName string, columnGroups map[string]*ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) error {
 	group := varName[len("SELECT_"):]
 	columns, found := columnGroups[group]
 	if !found {
-		panic(fmt.Sprintf("%v referenced column group not specified: %v", varName, columnGroups))
+		return ErrUnknownColumnGroup
 	}
 	buf.WriteString(Join(columns.Columns...))
+	return nil
 }
 
-func addVar_HINT(varName string, columnGroups map[string]*sql.ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) {
+func addVar_HINT(varName string, columnGroups map[string]*ColumnGroup, buf *bytes.Buffer, paramMap *nameToPositions, strParamMap *nameToPositions) error {
 	group := varName[len("HINT_"):]
 	columns, found := columnGroups[group]
 	if !found {
-		panic(fmt.Sprintf("%v referenced column group not specified: %v", varName, columnGroups))
+		return ErrUnknownColumnGroup
 	}
 	buf.WriteString(`/*{`)
 	for i, column := range columns.Columns {
@@ -284,4 +947,30 @@ func addVar_HINT(varName string, columnGroups map[string]*sql.ColumnGroup, buf *
 		buf.WriteString(`":"%v"`)
 	}
 	buf.WriteString(`}*/`)
+	return nil
+}
+
+// addVar_IN renders (?, ?, ?) for the list bound to the parameter, one element per argument
+func addVar_IN(varName string, buf *bytes.Buffer, paramMap *nameToPositions) {
+	paramMap.shapeParams[varName] = true
+	count, found := paramMap.shape.listLengths[varName]
+	if !found {
+		count = 1
+	}
+	if count == 0 {
+		// IN () is invalid syntax, IN (NULL) matches nothing
+		buf.WriteString("(NULL)")
+		if _, existing := paramMap.paramMap[varName]; !existing {
+			paramMap.paramMap[varName] = []int{}
+		}
+		return
+	}
+	buf.WriteByte('(')
+	for i, pos := range paramMap.addListParameter(varName, count) {
+		if i != 0 {
+			buf.WriteString(", ")
+		}
+		paramMap.dialect.writePlaceholder(buf, pos)
+	}
+	buf.WriteByte(')')
 }
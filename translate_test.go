@@ -2,6 +2,7 @@ package dingo
 
 import (
 	"github.com/stretchr/testify/require"
+	"github.com/v2pro/plz/sql"
 	"testing"
 )
 
@@ -78,6 +79,37 @@ func Test_translate_skip_quote(t *testing.T) {
 		`SELECT * FROM account_010 WHERE passenger_id="\":pid"`).sql)
 }
 
+func Test_translate_skip_backtick(t *testing.T) {
+	should := require.New(t)
+	translated := Translate("SELECT `a:b` FROM account_010 WHERE `x:y`=:pid").(*TranslatedSql)
+	should.Equal("SELECT `a:b` FROM account_010 WHERE `x:y`=?", translated.sql)
+	should.Equal(map[string][]int{
+		"pid": {0},
+	}, translated.paramMap)
+}
+
+func Test_translate_skip_line_comment(t *testing.T) {
+	should := require.New(t)
+	translated := Translate("SELECT * FROM account_010 -- it's :pid\nWHERE passenger_id=:pid").(*TranslatedSql)
+	should.Equal("SELECT * FROM account_010 -- it's :pid\nWHERE passenger_id=?", translated.sql)
+	should.Equal(1, translated.totalParamCount)
+	should.Equal("SELECT * FROM account_010 WHERE passenger_id=?-- :did", Translate(
+		"SELECT * FROM account_010 WHERE passenger_id=:pid-- :did").(*TranslatedSql).sql)
+	should.Equal("SELECT * FROM account_010 -- :pid", Translate(
+		"SELECT * FROM account_010 -- :pid").(*TranslatedSql).sql)
+	should.Equal("SELECT a-b FROM account_010", Translate(
+		"SELECT a-b FROM account_010").(*TranslatedSql).sql)
+}
+
+func Test_translate_skip_block_comment(t *testing.T) {
+	should := require.New(t)
+	translated := Translate("SELECT /* it's :pid */ * FROM account_010 WHERE passenger_id=:pid/**/").(*TranslatedSql)
+	should.Equal("SELECT /* it's :pid */ * FROM account_010 WHERE passenger_id=?/**/", translated.sql)
+	should.Equal(1, translated.totalParamCount)
+	should.Equal("SELECT * FROM account_010 /* :pid", Translate(
+		"SELECT * FROM account_010 /* :pid").(*TranslatedSql).sql)
+}
+
 func Test_translate_column_group(t *testing.T) {
 	should := require.New(t)
 	should.Equal("(a, b) VALUES (?, ?) (c, d) VALUES (?, ?)", Translate(
@@ -97,3 +129,195 @@ func Test_translate_HINT_COLUMNS(t *testing.T) {
 	should.Equal(`/*{"a":"%v","b":"%v"}*/`, Translate(
 		`:HINT_COLUMNS`, "a", "b").sql)
 }
+
+func Test_translate_postgres(t *testing.T) {
+	should := require.New(t)
+	translated := Translate(
+		"SELECT * FROM account_:STR_district WHERE passenger_id=:pid AND driver_id=:did OR passenger_id=:pid",
+		Postgres).(*TranslatedSql)
+	should.Equal("SELECT * FROM account_%v WHERE passenger_id=$1 AND driver_id=$2 OR passenger_id=$1", translated.sql)
+	should.Equal(map[string][]int{
+		"STR_district": {0},
+		"pid":          {1},
+		"did":          {2},
+	}, translated.paramMap)
+	should.Equal(3, translated.totalParamCount)
+	should.Equal(Postgres, translated.Dialect())
+}
+
+func Test_translate_postgres_column_group(t *testing.T) {
+	should := require.New(t)
+	should.Equal("INSERT test (a, b) VALUES ($1, $2)", Translate(
+		`INSERT test :INSERT_COLUMNS`, Postgres, "a", "b").(*TranslatedSql).sql)
+	should.Equal("UPDATE test SET a=$1, b=$2 WHERE a=$1", Translate(
+		`UPDATE test SET :UPDATE_COLUMNS WHERE a=:a`, Postgres, "a", "b").(*TranslatedSql).sql)
+	should.Equal("INSERT test (a, b) VALUES ($1, $2), ($3, $4)", Translate(
+		`INSERT test :BATCH_INSERT_COLUMNS`, Postgres,
+		sql.ColumnGroup{Group: "COLUMNS", Columns: []string{"a", "b"}, BatchInsertRowsCount: 2}).(*TranslatedSql).sql)
+}
+
+func Test_translate_skip_cast_and_assignment(t *testing.T) {
+	should := require.New(t)
+	translated := Translate("SELECT x::int, :pid::text FROM account_010 WHERE y::int=:did").(*TranslatedSql)
+	should.Equal("SELECT x::int, ?::text FROM account_010 WHERE y::int=?", translated.sql)
+	should.Equal(map[string][]int{
+		"pid": {0},
+		"did": {1},
+	}, translated.paramMap)
+	should.Equal("SET @v := 1, @w:=?", Translate(
+		"SET @v := 1, @w:=:pid").(*TranslatedSql).sql)
+	should.Equal("SELECT arr[1:2] FROM account_010", Translate(
+		"SELECT arr[1:2] FROM account_010").(*TranslatedSql).sql)
+}
+
+func Test_translate_dangling_colon(t *testing.T) {
+	should := require.New(t)
+	_, err := TranslateE("SELECT * FROM account_010 WHERE passenger_id=:")
+	should.Equal(&TranslateError{ErrMissingParameterName, "", 45,
+		"SELECT * FROM account_010 WHERE passenger_id=:"}, err)
+}
+
+func Test_translate_error(t *testing.T) {
+	should := require.New(t)
+	_, err := TranslateE("SELECT * FROM account_010 WHERE passenger_id=:Pid")
+	should.Equal(&TranslateError{ErrUpperCaseParameter, "Pid", 45,
+		"SELECT * FROM account_010 WHERE passenger_id=:Pid"}, err)
+	_, err = TranslateE("INSERT test :INSERT_COLUMNS1", "a")
+	should.Equal(&TranslateError{ErrUnknownColumnGroup, "INSERT_COLUMNS1", 12,
+		"INSERT test :INSERT_COLUMNS1"}, err)
+	_, err = TranslateE("INSERT test :INSERT_COLUMNS", 1)
+	should.Equal(&TranslateError{ErrUnexpectedColumnArgument, "1", -1,
+		"INSERT test :INSERT_COLUMNS"}, err)
+	should.Panics(func() {
+		Translate("INSERT test :INSERT_COLUMNS", 1)
+	})
+	_, err = TranslateE("DELETE FROM test WHERE :WHERE_KEYS", sql.ColumnGroup{Group: "KEYS"})
+	should.Equal(&TranslateError{ErrEmptyColumnGroup, "WHERE_KEYS", 23,
+		"DELETE FROM test WHERE :WHERE_KEYS"}, err)
+	_, err = TranslateE("DELETE FROM test WHERE a=:a AND :NULLABLE_OR_KEYS", sql.ColumnGroup{Group: "KEYS"})
+	should.Equal(ErrEmptyColumnGroup, err.(*TranslateError).Cause)
+	_, err = TranslateE("INSERT test :UPSERT_COLUMNS", UpsertColumns("COLUMNS", []string{"id"}))
+	should.Equal(&TranslateError{ErrEmptyColumnGroup, "UPSERT_COLUMNS", 12,
+		"INSERT test :UPSERT_COLUMNS"}, err)
+}
+
+func Test_translate_IN(t *testing.T) {
+	should := require.New(t)
+	translated := Translate("SELECT * FROM account WHERE entity_id IN :IN_ids AND event_id=:eid").(*TranslatedSql)
+	should.Equal("SELECT * FROM account WHERE entity_id IN (?) AND event_id=?", translated.sql)
+	should.Equal([]string{"IN_ids"}, translated.shapeParams)
+	translated = Translate("SELECT * FROM account WHERE entity_id IN :IN_ids AND event_id=:eid OR entity_id IN :IN_ids",
+		Postgres, bindShape{listLengths: map[string]int{"IN_ids": 3}}).(*TranslatedSql)
+	should.Equal("SELECT * FROM account WHERE entity_id IN ($1, $2, $3) AND event_id=$4 OR entity_id IN ($1, $2, $3)", translated.sql)
+	should.Equal(map[string][]int{
+		"IN_ids": {0, 1, 2},
+		"eid":    {3},
+	}, translated.paramMap)
+}
+
+func Test_translate_UPSERT_COLUMNS(t *testing.T) {
+	should := require.New(t)
+	should.Equal("INSERT test (id, a, b) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE a=VALUES(a), b=VALUES(b)", Translate(
+		`INSERT test :UPSERT_COLUMNS`, UpsertColumns("COLUMNS", []string{"id"}, "id", "a", "b")).(*TranslatedSql).sql)
+	should.Equal("INSERT test (id, a, b) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET a=EXCLUDED.a, b=EXCLUDED.b", Translate(
+		`INSERT test :UPSERT_COLUMNS`, Postgres, UpsertColumns("COLUMNS", []string{"id"}, "id", "a", "b")).(*TranslatedSql).sql)
+	should.Equal("INSERT test (id) VALUES ($1) ON CONFLICT (id) DO NOTHING", Translate(
+		`INSERT test :UPSERT_COLUMNS`, Postgres, UpsertColumns("COLUMNS", []string{"id"}, "id")).(*TranslatedSql).sql)
+	should.Equal("INSERT test (id) VALUES (?) ON DUPLICATE KEY UPDATE id=id", Translate(
+		`INSERT test :UPSERT_COLUMNS`, UpsertColumns("COLUMNS", []string{"id"}, "id")).(*TranslatedSql).sql)
+	_, err := TranslateE(`INSERT test :UPSERT_COLUMNS`, Postgres, "id", "a")
+	should.Equal(ErrMissingConflictKeys, err.(*TranslateError).Cause)
+}
+
+func Test_translate_WHERE_COLUMNS(t *testing.T) {
+	should := require.New(t)
+	translated := Translate(
+		`UPDATE test SET :UPDATE_COLUMNS1 WHERE :WHERE_COLUMNS2`,
+		sql.ColumnGroup{Group: "COLUMNS1", Columns: []string{"c"}},
+		sql.ColumnGroup{Group: "COLUMNS2", Columns: []string{"a", "b"}}).(*TranslatedSql)
+	should.Equal("UPDATE test SET c=? WHERE a=? AND b=?", translated.sql)
+	should.Equal(map[string][]int{
+		"c": {0},
+		"a": {1},
+		"b": {2},
+	}, translated.paramMap)
+	should.Equal("SELECT * FROM test WHERE c=$1 AND (a=$2 OR b=$3)", Translate(
+		`SELECT * FROM test WHERE c=:c AND :OR_COLUMNS`, Postgres, "a", "b").(*TranslatedSql).sql)
+}
+
+func Test_translate_NULLABLE_WHERE_COLUMNS(t *testing.T) {
+	should := require.New(t)
+	translated := Translate(`SELECT * FROM test WHERE :NULLABLE_WHERE_COLUMNS`, "a", "b").(*TranslatedSql)
+	should.Equal("SELECT * FROM test WHERE a=? AND b=?", translated.sql)
+	should.Equal([]string{"a", "b"}, translated.shapeParams)
+	translated = Translate(`SELECT * FROM test WHERE :NULLABLE_OR_COLUMNS`, "a", "b",
+		bindShape{nullColumns: map[string]bool{"a": true}}).(*TranslatedSql)
+	should.Equal("SELECT * FROM test WHERE (a IS NULL OR b=?)", translated.sql)
+	should.Equal(map[string][]int{
+		"a": {},
+		"b": {0},
+	}, translated.paramMap)
+	translated = Translate(`SELECT * FROM test WHERE :NULLABLE_WHERE_COLUMNS OR b=:b`, Postgres, "a", "b",
+		bindShape{nullColumns: map[string]bool{"b": true}}).(*TranslatedSql)
+	should.Equal("SELECT * FROM test WHERE a=$1 AND b IS NULL OR b=$2", translated.sql)
+	should.Equal(map[string][]int{
+		"a": {0},
+		"b": {1},
+	}, translated.paramMap)
+}
+
+func Test_translate_IF(t *testing.T) {
+	should := require.New(t)
+	template := "SELECT * FROM account WHERE entity_id=:eid /*IF status*/AND status=:status /*IF type*/AND type=:type/*END*//*END*/"
+	translated := Translate(template).(*TranslatedSql)
+	should.Equal("SELECT * FROM account WHERE entity_id=? ", translated.sql)
+	should.Equal(map[string][]int{
+		"eid":    {0},
+		"status": {},
+		"type":   {},
+	}, translated.paramMap)
+	should.Equal([]string{"status", "type"}, translated.shapeParams)
+	translated = Translate(template, bindShape{boundParams: map[string]bool{"status": true}}).(*TranslatedSql)
+	should.Equal("SELECT * FROM account WHERE entity_id=? AND status=? ", translated.sql)
+	translated = Translate(template, bindShape{boundParams: map[string]bool{"status": true, "type": true}}).(*TranslatedSql)
+	should.Equal("SELECT * FROM account WHERE entity_id=? AND status=? AND type=?", translated.sql)
+	template = "SELECT * FROM account WHERE entity_id=:eid /*IF status*/AND status IN :IN_status AND :WHERE_KEYS/*END*/ :LIMIT"
+	translated = Translate(template, sql.ColumnGroup{Group: "KEYS", Columns: []string{"type"}}).(*TranslatedSql)
+	should.Equal("SELECT * FROM account WHERE entity_id=?  ", translated.sql)
+	should.Equal(map[string][]int{
+		"eid":       {0},
+		"IN_status": {},
+		"type":      {},
+		"LIMIT":     {},
+	}, translated.paramMap)
+	translated = Translate(template, bindShape{boundParams: map[string]bool{"status": true}, listLengths: map[string]int{"IN_status": 2}},
+		sql.ColumnGroup{Group: "KEYS", Columns: []string{"type"}}).(*TranslatedSql)
+	should.Equal("SELECT * FROM account WHERE entity_id=? AND status IN (?, ?) AND type=? ", translated.sql)
+	template = "SELECT * FROM account WHERE entity_id=:eid /*IF type*/AND type IN :IN_ids/*END*/ AND status IN :IN_ids"
+	should.Equal("SELECT * FROM account WHERE entity_id=$1  AND status IN ($2)",
+		Translate(template, Postgres).(*TranslatedSql).sql)
+	should.Equal("SELECT * FROM account WHERE entity_id=?  AND status IN (?)",
+		Translate(template).(*TranslatedSql).sql)
+	template = "SELECT * FROM account WHERE /*IF since*/AND a>:since/*END*/ /*IF until*/AND b<:until AND c>:since/*END*/"
+	translated = Translate(template, Postgres, bindShape{boundParams: map[string]bool{"until": true}}).(*TranslatedSql)
+	should.Equal("SELECT * FROM account WHERE  AND b<$1 AND c>$2", translated.sql)
+	should.Equal(map[string][]int{"since": {1}, "until": {0}}, translated.paramMap)
+	_, err := TranslateE("SELECT * FROM account /*IF status*/")
+	should.Equal(ErrUnbalancedCondition, err.(*TranslateError).Cause)
+	_, err = TranslateE("SELECT * FROM account /*END*/")
+	should.Equal(ErrUnbalancedCondition, err.(*TranslateError).Cause)
+}
+
+func Test_translated_sql_introspection(t *testing.T) {
+	should := require.New(t)
+	translated := Translate("SELECT * FROM account_:STR_district WHERE passenger_id=:pid AND "+
+		"driver_id=:did /*IF status*/AND status=:status/*END*/ OR passenger_id=:pid :HINT_COLUMNS", "a").(*TranslatedSql)
+	should.Equal(`SELECT * FROM account_%v WHERE passenger_id=? AND driver_id=?  OR passenger_id=? /*{"a":"%v"}*/`,
+		translated.SQL())
+	should.Equal([]string{"pid", "did", "status"}, translated.ParamNames())
+	should.Equal([]string{"STR_district", "a"}, translated.StrParamNames())
+	should.Equal([]int{2, 4}, translated.Positions("pid"))
+	should.Equal([]int{}, translated.Positions("status"))
+	should.Equal([]string{"a", "did"}, translated.Unbound("STR_district", "010", "pid", 1))
+	should.Equal([]string{}, translated.Unbound("STR_district", "010", "pid", 1, "did", 2, "a", 3))
+}